@@ -1,10 +1,12 @@
 module lubyshev/go-site-benchmark
 
-go 1.15
+go 1.16
 
 require (
 	github.com/PuerkitoBio/goquery v1.7.1
+	github.com/alicebob/miniredis/v2 v2.14.3
 	github.com/joho/godotenv v1.3.0
 	github.com/stretchr/testify v1.7.0
 	github.com/valyala/fasthttp v1.30.0
+	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
 )
@@ -7,7 +7,11 @@ import (
 	"lubyshev/go-site-benchmark/src/benchmark"
 	"lubyshev/go-site-benchmark/src/cache"
 	"lubyshev/go-site-benchmark/src/conf"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/domain"
 	"lubyshev/go-site-benchmark/src/handlers"
+	"lubyshev/go-site-benchmark/src/jobs"
+	"lubyshev/go-site-benchmark/src/yandex"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -35,14 +39,52 @@ func main() {
 	config := conf.GetConfig()
 	log.Println("Starting background ...")
 
+	switch config.CacheStorage {
+	case conf.CacheStorageFile:
+		storage, err := cache.NewFileStorage(config.CacheFile)
+		if err != nil {
+			log.Fatalf("ERROR: %s\n", err.Error())
+		}
+		_ = cache.GetCache().SetStorage(storage)
+	case conf.CacheStorageRedis:
+		storage, err := cache.NewRedisStorage(
+			config.CacheRedisAddr,
+			config.CacheRedisPassword,
+			config.CacheRedisDb,
+			config.CacheRedisPrefix,
+		)
+		if err != nil {
+			log.Fatalf("ERROR: %s\n", err.Error())
+		}
+		_ = cache.GetCache().SetStorage(storage)
+	}
+
+	err := cache.GetCache().SetLimits(cache.Limits{
+		MaxItems: config.CacheMaxItems,
+		MaxBytes: config.CacheMaxMemory,
+	})
+	if err != nil {
+		log.Fatalf("ERROR: %s\n", err.Error())
+	}
+
 	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
-	err := overload.StartBackground(
-		config.OverloadWorkers,
-		config.OverloadInitConnections,
-		config.OverloadMaxLimit,
-		config.OverloadMaxConnections,
-		config.OverloadMethod,
-	)
+	err = overload.StartBackground(benchmark.OverloadOptions{
+		WorkersCount:         config.OverloadWorkers,
+		InitConnectionsCount: config.OverloadInitConnections,
+		MaxLimit:             config.OverloadMaxLimit,
+		MaxConnections:       config.OverloadMaxConnections,
+		Method:               config.OverloadMethod,
+		ResponseTimeout:      config.OverloadResponseTimeout,
+		BisectTolerance:      config.OverloadBisectTolerance,
+		StepRounds:           config.OverloadStepRounds,
+		StepMaxErrorRate:     config.OverloadStepErrorRate,
+		Mode:                 config.OverloadMode,
+		SustainDuration:      config.OverloadSustainDuration,
+		RateLimitMarkers:     config.OverloadRateLimitMarkers,
+		RespectRobots:        config.OverloadRespectRobots,
+		UserAgent:            config.OverloadUserAgent,
+		StaleTtl:             config.CacheStaleTtl,
+	})
 	if err != nil {
 		log.Fatalf("ERROR: %s\n", err.Error())
 	}
@@ -57,13 +99,42 @@ func main() {
 		log.Fatalf("ERROR: %s\n", err.Error())
 	}
 
-	log.Printf("Listen on http://localhost:%d with config %+v", config.ServerPort, config)
+	jobs.GetManager().Configure(config.JobsHistory, config.JobsTimeout, config.CacheTtl)
+	dataProvider.AllowPrivateHosts(config.BenchmarkAllowPrivate)
+	dataProvider.ConfigureSitemap(config.SitemapSampleSize, config.SitemapMaxFiles, config.SitemapConcurrency, config.SitemapCacheTtl)
+	err = dataProvider.ConfigureYandex(yandex.SearchParams{
+		Region: config.YandexRegion,
+		Pages:  config.YandexPages,
+		NumDoc: config.YandexNumDoc,
+		Device: config.YandexDevice,
+	})
+	if err != nil {
+		log.Fatalf("ERROR: %s\n", err.Error())
+	}
+	yandex.ConfigureBackoff(config.YandexBackoffMin, config.YandexBackoffMax)
+	if config.DomainSuffixList != "" {
+		if err = domain.LoadList(config.DomainSuffixList); err != nil {
+			log.Fatalf("ERROR: %s\n", err.Error())
+		}
+	}
+
+	// the logged copy does not disclose the redis password
+	logged := *config
+	if logged.CacheRedisPassword != "" {
+		logged.CacheRedisPassword = "***"
+	}
+	log.Printf("Listen on http://localhost:%d with config %+v", config.ServerPort, &logged)
 
 	signals := make(chan os.Signal, 1)
 	done := make(chan bool, 1)
 
 	server := &http.Server{Addr: fmt.Sprintf(":%d", config.ServerPort)}
 	http.HandleFunc("/sites", handlers.Site)
+	http.HandleFunc("/sites/stream", handlers.SiteStream)
+	http.HandleFunc("/benchmark", handlers.Benchmark)
+	http.HandleFunc("/jobs", handlers.Jobs)
+	http.HandleFunc("/jobs/", handlers.Jobs)
+	http.HandleFunc("/cache/stats", handlers.CacheStats)
 	go func() {
 		err = server.ListenAndServe()
 		if err != nil {
@@ -85,5 +156,8 @@ func main() {
 	overload.StopBackground()
 	ctxCacheCancelFunc()
 	_ = server.Close()
+	if err = cache.GetCache().Close(); err != nil {
+		log.Printf("ERROR: %s\n", err.Error())
+	}
 	time.Sleep(3 * time.Second)
 }
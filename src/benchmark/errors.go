@@ -0,0 +1,96 @@
+package benchmark
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"syscall"
+)
+
+const (
+	ErrorClassTimeout     = "timeout"
+	ErrorClassConnRefused = "connection_refused"
+	ErrorClassConnReset   = "connection_reset"
+	ErrorClassTls         = "tls_handshake"
+	ErrorClassDns         = "dns"
+	ErrorClassHttp4xx     = "http_4xx"
+	ErrorClassHttp5xx     = "http_5xx"
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassRedirect    = "redirect"
+	ErrorClassHttpOther   = "http_other"
+	ErrorClassSlow        = "slow"
+	ErrorClassOther       = "other"
+)
+
+type ErrorCounts map[string]int
+
+func (ec ErrorCounts) add(other ErrorCounts) {
+	for class, count := range other {
+		ec[class] += count
+	}
+}
+
+func (ec ErrorCounts) Total() int {
+	total := 0
+	for _, count := range ec {
+		total += count
+	}
+	return total
+}
+
+// ClassifyStatus returns the error class of the final response status,
+// empty for a success. Redirects are followed by the loader, so a redirect
+// status means a redirect which could not be followed.
+func ClassifyStatus(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return ""
+	case status == fasthttp.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case status >= 500:
+		return ErrorClassHttp5xx
+	case status >= 400:
+		return ErrorClassHttp4xx
+	case status >= 300:
+		return ErrorClassRedirect
+	}
+	return ErrorClassHttpOther
+}
+
+func ClassifyTransportError(err error) string {
+	if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) {
+		return ErrorClassTimeout
+	}
+	if errors.Is(err, fasthttp.ErrTooManyRedirects) || errors.Is(err, fasthttp.ErrMissingLocation) {
+		return ErrorClassRedirect
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDns
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassConnRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, fasthttp.ErrConnectionClosed) {
+		return ErrorClassConnReset
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certErr) ||
+		strings.HasPrefix(err.Error(), "tls: ") {
+		return ErrorClassTls
+	}
+	return ErrorClassOther
+}
@@ -0,0 +1,67 @@
+package benchmark
+
+import (
+	"sync"
+	"time"
+)
+
+const eventsBufferSize = 256
+
+// StepEvent is published when a step of a host is done and the next state
+// is chosen: Concurrency, Errors and Latency belong to the done step,
+// PrevState is the state served to the clients during the step.
+type StepEvent struct {
+	Time            time.Time
+	Host            string
+	Urls            []string
+	Concurrency     int
+	Errors          int
+	FailedRequests  int
+	Requests        int
+	ErrorClasses    ErrorCounts
+	Latency         LatencyStats
+	Timeline        [][]Interval
+	PrevState       string
+	State           string
+	Count           int
+	NextConcurrency int
+}
+
+type eventBus struct {
+	subscribers map[chan *StepEvent]struct{}
+	mx          sync.RWMutex
+}
+
+var events = &eventBus{subscribers: make(map[chan *StepEvent]struct{})}
+
+func (b *eventBus) subscribe() (<-chan *StepEvent, func()) {
+	ch := make(chan *StepEvent, eventsBufferSize)
+	b.mx.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mx.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mx.Lock()
+			delete(b.subscribers, ch)
+			b.mx.Unlock()
+		})
+	}
+}
+
+// publish never blocks the queue workers: slow subscribers lose events.
+func (b *eventBus) publish(e *StepEvent) {
+	defer b.mx.RUnlock()
+	b.mx.RLock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (o overload) Subscribe() (<-chan *StepEvent, func()) {
+	return events.subscribe()
+}
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"sort"
+	"time"
+)
+
+type LatencyStats struct {
+	Min time.Duration
+	Avg time.Duration
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// NewLatencyStats summarizes the latencies, percentiles use the nearest-rank method.
+func NewLatencyStats(latencies []time.Duration) LatencyStats {
+	stats := LatencyStats{}
+	l := len(latencies)
+	if l == 0 {
+		return stats
+	}
+	sorted := make([]time.Duration, l)
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	stats.Min = sorted[0]
+	stats.Max = sorted[l-1]
+	stats.Avg = sum / time.Duration(l)
+	stats.P50 = percentile(sorted, 50)
+	stats.P95 = percentile(sorted, 95)
+	stats.P99 = percentile(sorted, 99)
+
+	return stats
+}
+
+// percentile uses the nearest-rank method on an already sorted slice.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
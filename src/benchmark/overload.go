@@ -1,6 +1,7 @@
 package benchmark
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"lubyshev/go-site-benchmark/src/cache"
@@ -10,38 +11,339 @@ import (
 )
 
 type OverloadTest interface {
-	Benchmark(sites *dataProvider.HostsToCheck, ttl time.Duration) (map[string]int, error)
-	StartBackground(
-		workersCount int,
-		initConnectionsCount int,
-		maxLimit int,
-		maxConnections int,
-		method string,
-	) error
+	// Benchmark waits for the hosts to be measured until ctx is done
+	// and returns partial results for the rest of them
+	Benchmark(ctx context.Context, sites *dataProvider.HostsToCheck, ttl time.Duration) (map[string]*HostResult, error)
+	// Progress returns the current results of the hosts without queueing them
+	Progress(sites *dataProvider.HostsToCheck) map[string]*HostResult
+	// Cancel returns the current results of the hosts and stops measuring the ones
+	// no Benchmark call waits for, they are measured again by the next request
+	Cancel(sites *dataProvider.HostsToCheck) map[string]*HostResult
+	// Subscribe returns the step events of all hosts, the returned func unsubscribes
+	Subscribe() (<-chan *StepEvent, func())
+	StartBackground(options OverloadOptions) error
 	StopBackground()
 }
 
+type OverloadOptions struct {
+	WorkersCount         int
+	InitConnectionsCount int
+	MaxLimit             int
+	MaxConnections       int
+	Method               string
+	ResponseTimeout      time.Duration
+	BisectTolerance      int
+	// every step is repeated StepRounds times and passes
+	// when no more than StepMaxErrorRate percent of requests failed
+	StepRounds       int
+	StepMaxErrorRate float64
+	// ModeBurst fires one request per connection, ModeSustained keeps
+	// every connection busy for SustainDuration
+	Mode            string
+	SustainDuration time.Duration
+	// 2xx, 403 and 503 responses containing one of the markers are treated as rate limited
+	RateLimitMarkers []string
+	// RespectRobots skips urls disallowed by robots.txt for UserAgent
+	// and caps the recommendation by the Crawl-delay
+	RespectRobots bool
+	UserAgent     string
+	// results older than the test ttl are served for StaleTtl more
+	// while the host is measured again
+	StaleTtl time.Duration
+}
+
+const (
+	ModeBurst     = "burst"
+	ModeSustained = "sustained"
+)
+
+func (o OverloadOptions) Validate() error {
+	if _, err := GetStrategy(o.Method); err != nil {
+		return fmt.Errorf("invalid overload metod: %s", o.Method)
+	}
+	switch o.Mode {
+	case ModeBurst, ModeSustained:
+	default:
+		return fmt.Errorf("invalid overload mode: %s", o.Mode)
+	}
+	if o.Mode == ModeSustained && o.SustainDuration <= 0 {
+		return fmt.Errorf("invalid overload sustain duration: %s", o.SustainDuration)
+	}
+	if o.StepRounds < 1 {
+		return fmt.Errorf("invalid overload step rounds: %d", o.StepRounds)
+	}
+	// the bisect method never narrows the interval to a zero tolerance
+	if o.BisectTolerance < 1 {
+		return fmt.Errorf("invalid overload bisect tolerance: %d", o.BisectTolerance)
+	}
+
+	return nil
+}
+
 const (
-	stateUrlInProgress = "in progress"
-	stateUrlReady      = "ready"
-	stateUrlFailed     = "failed"
+	StateUrlInProgress = "in progress"
+	StateUrlReady      = "ready"
+	StateUrlFailed     = "failed"
+	StateUrlNotTested  = "not tested"
+	// in progress without any finished step
+	StateUrlQueued = "queued"
 )
 
+// Url is the overload test of a host: requests of every step
+// are spread across the host Urls, Url is the first of them.
 type Url struct {
-	Url      string
-	Count    int
-	state    string
-	ttl      time.Duration
-	attempts int
-	errors   int
+	Host  string
+	Url   string
+	Urls  []string
+	Count int
+	Steps []Step
+	// urls skipped by robots.txt and the Crawl-delay of the host
+	Disallowed []string
+	CrawlDelay time.Duration
+	// zero while the host is in progress
+	MeasuredAt time.Time
+	state      string
+	ttl        time.Duration
+	attempts   int
+	errors     int
+	// a new test of a stale result, its steps are not cached
+	revalidate bool
+	staleState string
+	// cancelled when nobody waits for the host anymore
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type Step struct {
+	Attempts int
+	// errors per round, zero when the step error rate is acceptable
+	Errors         int
+	Rounds         int
+	PassedRounds   int
+	Requests       int
+	FailedRequests int
+	ErrorClasses   ErrorCounts
+	Latency        LatencyStats
+	// requests per second and the per second timeline of every round
+	Throughput float64
+	Timeline   [][]Interval
+	// the longest Retry-After advertised by the site during the step
+	RetryAfter time.Duration
+}
+
+type Interval struct {
+	Requests int
+	Errors   int
+}
+
+// StepErrors scales the failed requests of a step to errors per connection:
+// a connection of the sustained mode sends many requests every round.
+func StepErrors(attempts int, requests int, failedRequests int) int {
+	if requests == 0 || failedRequests == 0 {
+		return 0
+	}
+	errs := (attempts*failedRequests + requests - 1) / requests
+	if errs > attempts {
+		errs = attempts
+	}
+	return errs
+}
+
+func (u *Url) cacheKey() string {
+	return overloadCacheKey(u.Host)
+}
+
+func overloadCacheKey(host string) string {
+	return "overload::" + host
+}
+
+// leaseCacheKey marks the host measured by a replica.
+func leaseCacheKey(host string) string {
+	return "overload-lease::" + host
+}
+
+// copy returns the public part of the url.
+func (u *Url) copy() *Url {
+	return &Url{
+		Host:  u.Host,
+		Url:   u.Url,
+		Urls:  append([]string(nil), u.Urls...),
+		Count: u.Count,
+		Steps: append([]Step(nil), u.Steps...),
+		state: u.state,
+
+		Disallowed: append([]string(nil), u.Disallowed...),
+		CrawlDelay: u.CrawlDelay,
+		MeasuredAt: u.MeasuredAt,
+		ttl:        u.ttl,
+	}
+}
+
+// servedState is the state of the host the clients get while the url is measured.
+func (u *Url) servedState() string {
+	switch {
+	case u.revalidate:
+		return u.staleState
+	case len(u.Steps) == 0:
+		return StateUrlQueued
+	}
+	return u.state
+}
+
+// stale reports whether the final result is older than its ttl.
+func (u *Url) stale() bool {
+	return u.state != StateUrlInProgress && !u.MeasuredAt.IsZero() && time.Since(u.MeasuredAt) > u.ttl
+}
+
+func (u *Url) errorClasses() ErrorCounts {
+	res := make(ErrorCounts)
+	for _, step := range u.Steps {
+		res.add(step.ErrorClasses)
+	}
+	return res
+}
+
+// confidence is the share of passed rounds among the steps
+// not above the recommended count.
+func (u *Url) confidence() float64 {
+	rounds, passed := 0, 0
+	for _, step := range u.Steps {
+		if step.Attempts <= u.Count {
+			rounds += step.Rounds
+			passed += step.PassedRounds
+		}
+	}
+	if rounds == 0 {
+		return 0
+	}
+	return float64(passed) / float64(rounds)
+}
+
+// recommendedStep is the largest step not above the recommended count.
+func (u *Url) recommendedStep() *Step {
+	var res *Step
+	for i := range u.Steps {
+		if u.Steps[i].Attempts <= u.Count && (res == nil || u.Steps[i].Attempts > res.Attempts) {
+			res = &u.Steps[i]
+		}
+	}
+	return res
+}
+
+func (u *Url) throughput() float64 {
+	if step := u.recommendedStep(); step != nil {
+		return step.Throughput
+	}
+	return 0
+}
+
+// rateLimit returns the lowest count the site answered with a rate limit on.
+func (u *Url) rateLimit() (limitedAt int, retryAfter time.Duration) {
+	for _, step := range u.Steps {
+		if step.ErrorClasses[ErrorClassRateLimited] == 0 {
+			continue
+		}
+		if limitedAt == 0 || step.Attempts < limitedAt {
+			limitedAt = step.Attempts
+		}
+		if step.RetryAfter > retryAfter {
+			retryAfter = step.RetryAfter
+		}
+	}
+	return
+}
+
+// latency is taken from the recommended step, or from the last one
+// when there is no recommendation yet.
+func (u *Url) latency() LatencyStats {
+	if step := u.recommendedStep(); step != nil {
+		return step.Latency
+	}
+	return u.lastLatency()
+}
+
+// capByCrawlDelay lowers the recommended count to the Crawl-delay limit.
+func (u *Url) capByCrawlDelay() {
+	limit := crawlDelayLimit(u.CrawlDelay, u.latency().Avg)
+	if limit > 0 && u.Count > limit {
+		log.Printf("%s recommendation %d capped to %d by crawl-delay %s", u.Host, u.Count, limit, u.CrawlDelay)
+		u.Count = limit
+	}
+}
+
+// timeline is taken from the same step as the latency.
+func (u *Url) timeline() [][]Interval {
+	if step := u.recommendedStep(); step != nil {
+		return step.Timeline
+	}
+	if l := len(u.Steps); l > 0 {
+		return u.Steps[l-1].Timeline
+	}
+	return nil
+}
+
+func (u *Url) lastLatency() LatencyStats {
+	if l := len(u.Steps); l > 0 {
+		return u.Steps[l-1].Latency
+	}
+	return LatencyStats{}
+}
+
+type HostResult struct {
+	State string
+	// Count is a partial result while the host is in progress
+	Partial    bool
+	Urls       []string
+	Count      int
+	Confidence float64
+	Throughput float64
+	Errors     ErrorCounts
+	Latency    LatencyStats
+	Timeline   [][]Interval
+	// zero when the host did not limit requests
+	RateLimitedAt int
+	RetryAfter    time.Duration
+	Disallowed    []string
+	CrawlDelay    time.Duration
+	// a stale result is served while the host is measured again
+	Stale bool
+	Age   time.Duration
 }
 
-type Host struct {
-	Urls map[string]*Url
+// Done reports whether the host result is final.
+func (hr *HostResult) Done() bool {
+	return hr.State == StateUrlReady || hr.State == StateUrlFailed || hr.State == StateUrlNotTested
+}
+
+func newHostResult(url *Url) *HostResult {
+	state := url.state
+	if state == StateUrlInProgress && len(url.Steps) == 0 {
+		state = StateUrlQueued
+	}
+	res := &HostResult{
+		State:      state,
+		Urls:       url.Urls,
+		Count:      url.Count,
+		Confidence: url.confidence(),
+		Throughput: url.throughput(),
+		Errors:     url.errorClasses(),
+		Latency:    url.latency(),
+		Timeline:   url.timeline(),
+	}
+	res.Partial = state == StateUrlInProgress
+	res.RateLimitedAt, res.RetryAfter = url.rateLimit()
+	res.Disallowed = url.Disallowed
+	res.CrawlDelay = url.CrawlDelay
+	if !url.MeasuredAt.IsZero() {
+		res.Age = time.Since(url.MeasuredAt)
+		res.Stale = url.stale()
+	}
+
+	return res
 }
 
 type OverloadTestResult struct {
-	Items map[string]*Host
+	Items map[string]*Url
 	lock  sync.RWMutex
 }
 
@@ -51,10 +353,7 @@ func (otr *OverloadTestResult) set(host string, url *Url) error {
 	if _, ok := otr.Items[host]; !ok {
 		return fmt.Errorf("host %s is not initialized", host)
 	}
-	if otr.Items[host].Urls == nil {
-		otr.Items[host].Urls = make(map[string]*Url, 0)
-	}
-	otr.Items[host].Urls[url.Url] = url
+	otr.Items[host] = url
 
 	return nil
 }
@@ -64,19 +363,12 @@ func (otr *OverloadTestResult) clone() *OverloadTestResult {
 	otr.lock.Lock()
 	clone := new(OverloadTestResult)
 	if otr.Items != nil {
-		clone.Items = make(map[string]*Host)
-		for hostName, host := range otr.Items {
-			clone.Items[hostName] = new(Host)
-			if otr.Items[hostName].Urls != nil {
-				urls := make(map[string]*Url)
-				for urlName, url := range host.Urls {
-					urls[urlName] = &Url{
-						Url:   url.Url,
-						Count: url.Count,
-					}
-				}
-				clone.Items[hostName].Urls = urls
+		clone.Items = make(map[string]*Url)
+		for hostName, url := range otr.Items {
+			if url != nil {
+				url = url.copy()
 			}
+			clone.Items[hostName] = url
 		}
 	}
 
@@ -87,42 +379,88 @@ type overload struct{}
 
 var overloadManager overload
 
+const benchmarkPollInterval = 250 * time.Millisecond
+
 func (o overload) Benchmark(
+	ctx context.Context,
+	sites *dataProvider.HostsToCheck,
+	ttl time.Duration,
+) (res map[string]*HostResult, err error) {
+	getQueue().addWaiters(sites, 1)
+	defer getQueue().addWaiters(sites, -1)
+	ticker := time.NewTicker(benchmarkPollInterval)
+	defer ticker.Stop()
+	for {
+		res = o.collect(ctx, sites, ttl, true)
+		done := true
+		for _, host := range res {
+			done = done && host.Done()
+		}
+		if done {
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return res, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o overload) Progress(sites *dataProvider.HostsToCheck) map[string]*HostResult {
+	return o.collect(context.Background(), sites, 0, false)
+}
+
+func (o overload) Cancel(sites *dataProvider.HostsToCheck) map[string]*HostResult {
+	res := o.Progress(sites)
+	getQueue().cancelUnwaited(sites)
+	return res
+}
+
+// collect returns the current results of the hosts, the ones
+// not in cache are pushed to the queue if push is set.
+func (o overload) collect(
+	ctx context.Context,
 	sites *dataProvider.HostsToCheck,
 	ttl time.Duration,
-) (res map[string]int, err error) {
+	push bool,
+) (res map[string]*HostResult) {
 	result := new(OverloadTestResult)
-	result.Items = make(map[string]*Host)
+	result.Items = make(map[string]*Url)
 
 	var wg sync.WaitGroup
-	for host, url := range sites.Items {
+	for host, urls := range sites.Items {
 		if _, ok := result.Items[host]; !ok {
 			result.lock.Lock()
-			result.Items[host] = new(Host)
+			result.Items[host] = nil
 			result.lock.Unlock()
 			wg.Add(1)
-			go o.testSite(host, url, ttl, result, &wg)
+			go o.testSite(ctx, host, urls, ttl, push, result, &wg)
 		}
 	}
 	wg.Wait()
-	res = make(map[string]int)
-	for hostName, host := range result.Items {
-		res[hostName] = 0
-		if l := len(host.Urls); l > 0 {
-			for _, url := range host.Urls {
-				res[hostName] += url.Count
+	res = make(map[string]*HostResult)
+	for hostName, url := range result.Items {
+		if url == nil {
+			res[hostName] = &HostResult{
+				State:  StateUrlNotTested,
+				Urls:   sites.Items[hostName],
+				Errors: make(ErrorCounts),
 			}
-			res[hostName] /= l
+			continue
 		}
+		res[hostName] = newHostResult(url)
 	}
 
 	return
 }
 
 func (o *overload) testSite(
+	ctx context.Context,
 	host string,
 	urls []string,
 	ttl time.Duration,
+	push bool,
 	result *OverloadTestResult,
 	wg *sync.WaitGroup,
 ) {
@@ -130,25 +468,68 @@ func (o *overload) testSite(
 		wg.Done()
 	}()
 
-	for _, url := range urls {
-		cachedUrl, err := getQueue().getUrl(url)
-		if err == cache.ErrNotExists {
-			// move to queue
-			getQueue().push(&Url{
-				state: stateUrlInProgress,
-				ttl:   ttl,
-				Url:   url,
-			})
-			continue
+	if len(urls) == 0 {
+		return
+	}
+	cachedUrl, err := getQueue().getUrl(host)
+	if err == nil && getQueue().orphaned(cachedUrl) {
+		err = cache.ErrNotExists
+	}
+	if err == cache.ErrNotExists && !push {
+		return
+	}
+	revalidate := err == nil && push && cachedUrl.stale() && !getQueue().leased(host)
+	if err == cache.ErrNotExists || revalidate {
+		url := &Url{
+			state:      StateUrlInProgress,
+			ttl:        ttl,
+			Host:       host,
+			Url:        urls[0],
+			Urls:       append([]string(nil), urls...),
+			revalidate: revalidate,
 		}
-		if err != nil {
-			log.Printf("ERROR: %s\n", err.Error())
-			continue
+		if revalidate {
+			url.staleState = cachedUrl.state
 		}
-
-		err = result.set(host, cachedUrl)
-		if err != nil {
-			log.Printf("ERROR: %s\n", err.Error())
+		if getQueue().respectRobots {
+			url.Urls, url.Disallowed, url.CrawlDelay, err = getQueue().filterRobots(ctx, urls)
+			if err != nil {
+				// the request is over, robots.txt will be checked by the next one
+				return
+			}
+			if len(url.Urls) == 0 {
+				url.state = StateUrlNotTested
+				url.Url = ""
+				url.MeasuredAt = time.Now()
+				log.Printf("%s is not tested: all urls are disallowed by robots.txt", host)
+				cacheUrl(url)
+				_ = result.set(host, url.copy())
+				return
+			}
+			url.Url = url.Urls[0]
+		}
+		// move to queue
+		queued := url.copy()
+		switch {
+		case revalidate:
+			// the stale result is served until the new one is ready
+			if getQueue().push(url) {
+				log.Printf("%s result is stale, measuring again", host)
+			}
+		case getQueue().push(url):
+			cachedUrl, err = queued, nil
+		default:
+			// pushed by a concurrent request
+			cachedUrl, err = getQueue().getUrl(host)
 		}
 	}
+	if err != nil {
+		log.Printf("ERROR: %s\n", err.Error())
+		return
+	}
+
+	err = result.set(host, cachedUrl)
+	if err != nil {
+		log.Printf("ERROR: %s\n", err.Error())
+	}
 }
@@ -8,8 +8,9 @@ import (
 	"github.com/valyala/fasthttp"
 	"log"
 	"lubyshev/go-site-benchmark/src/cache"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"os"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -18,73 +19,101 @@ const (
 	stateQueueStopped = "stopped"
 )
 
-const (
-	methodSimple = "simple"
-	methodStrong = "strong"
-)
-
 var (
 	ErrAlreadyStarted = errors.New("already started")
 )
 
+const maxRedirects = 5
+
+// leaseTtl is the time other replicas wait for a host in progress
+// after its replica has gone.
+const leaseTtl = 30 * time.Second
+
 type overloadQueue struct {
 	state                string
 	urls                 []*Url
 	mxUrls               sync.Mutex
+	active               map[string]*Url // queued and running urls by host
+	waiters              map[string]int  // Benchmark calls waiting for a host
+	replica              string          // lease owner, unique per process
 	chUrls               chan *Url
+	stopped              chan struct{} // closed when the workers are finished
 	ctx                  context.Context
 	cancel               context.CancelFunc
 	workersCount         int
 	initConnectionsCount int
 	maxLimit             int
 	maxConnections       int
-	method               string
+	strategy             Strategy
+	responseTimeout      time.Duration
+	bisectTolerance      int
+	stepRounds           int
+	stepMaxErrorRate     float64
+	mode                 string
+	sustainDuration      time.Duration
+	rateLimitMarkers     [][]byte
+	respectRobots        bool
+	userAgent            string
+	staleTtl             time.Duration
 }
 
-func (q *overloadQueue) start(
-	count int,
-	connectionsCount int,
-	limit int,
-	connections int,
-	method string,
-) error {
+func (q *overloadQueue) start(options OverloadOptions) error {
 	if q.state == stateQueueStarted {
 		return ErrAlreadyStarted
 	}
-	switch method {
-	case methodSimple:
-		q.method = methodSimple
-	case methodStrong:
-		q.method = methodStrong
-	default:
-		return fmt.Errorf("invalid overload metod: %s", method)
+	if err := options.Validate(); err != nil {
+		return err
 	}
+	strategy, _ := GetStrategy(options.Method)
+	q.strategy = strategy
 	q.state = stateQueueStarted
-	q.workersCount = count
-	q.initConnectionsCount = connectionsCount
-	q.maxLimit = limit
-	q.maxConnections = connections
+	q.workersCount = options.WorkersCount
+	q.initConnectionsCount = options.InitConnectionsCount
+	q.maxLimit = options.MaxLimit
+	q.maxConnections = options.MaxConnections
+	q.responseTimeout = options.ResponseTimeout
+	q.bisectTolerance = options.BisectTolerance
+	q.stepRounds = options.StepRounds
+	q.stepMaxErrorRate = options.StepMaxErrorRate
+	q.mode = options.Mode
+	q.sustainDuration = options.SustainDuration
+	q.rateLimitMarkers = make([][]byte, 0, len(options.RateLimitMarkers))
+	for _, marker := range options.RateLimitMarkers {
+		q.rateLimitMarkers = append(q.rateLimitMarkers, bytes.ToLower([]byte(marker)))
+	}
+	q.respectRobots = options.RespectRobots
+	q.userAgent = options.UserAgent
+	q.staleTtl = options.StaleTtl
 
 	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.stopped = make(chan struct{})
 
 	go q._pusher(q.ctx)
-	go q._start()
+	go q._leaser(q.ctx)
+	go q._start(q.ctx, q.workersCount, q.stopped)
 
 	return nil
 }
 
-func (q *overloadQueue) _start() {
+func (q *overloadQueue) _start(ctx context.Context, workersCount int, stopped chan struct{}) {
+	defer close(stopped)
 	wg := sync.WaitGroup{}
-	wg.Add(q.workersCount)
-	for i := 0; i < q.workersCount; i++ {
-		go q.worker(i, q.chUrls, q.ctx, &wg)
+	wg.Add(workersCount)
+	for i := 0; i < workersCount; i++ {
+		go q.worker(i, q.chUrls, ctx, &wg)
 	}
 	wg.Wait()
-	q.state = stateQueueStarted
 }
 
+// stop returns when the workers are finished, so the queue
+// can be started again with other options.
 func (q *overloadQueue) stop() {
+	if q.state != stateQueueStarted {
+		return
+	}
 	q.cancel()
+	<-q.stopped
+	q.state = stateQueueStopped
 }
 
 func (q *overloadQueue) worker(
@@ -132,53 +161,230 @@ func (q *overloadQueue) releaseConnections(count int) bool {
 }
 
 func (q *overloadQueue) testUrl(_ int, url *Url) {
-	if url.state != stateUrlInProgress {
+	if url.state != StateUrlInProgress {
 		return
 	}
-	if url.errors >= 0 {
-		url.state, url.Count, url.attempts = q.nextStep(url)
-		if url.state != stateUrlInProgress {
-			cache.GetCache().Set(url.Url, url, url.ttl)
-			log.Printf(
-				"%s tested on %d connections and has %d errors",
-				url.Url,
-				url.Count,
-				url.errors,
-			)
-			return
-		}
-		url.errors = -1
+	if url.ctx.Err() != nil {
+		q.drop(url)
+		return
+	}
+	// the first step of a new url
+	if url.errors >= 0 && !q.next(url, url.servedState()) {
+		return
 	}
 
-	errorsCount := int32(0)
-	if q.allocateConnections(url.attempts) {
-		wg := sync.WaitGroup{}
-		for i := 0; i < url.attempts; i++ {
-			wg.Add(1)
-			go q.loadUrl(url.Url, &errorsCount, &wg)
-		}
-		wg.Wait()
-		q.releaseConnections(url.attempts)
-	} else {
+	if !q.allocateConnections(url.attempts) {
 		q.pushForced(url)
 		return
 	}
-	url.errors = int(errorsCount)
+	step := Step{
+		Attempts:     url.attempts,
+		Rounds:       q.stepRounds,
+		ErrorClasses: make(ErrorCounts),
+	}
+	latencies := make([]time.Duration, 0, url.attempts*q.stepRounds)
+	var elapsed time.Duration
+	for round := 0; round < q.stepRounds && url.ctx.Err() == nil; round++ {
+		results, roundElapsed := q.runRound(url.ctx, url.Urls, url.attempts)
+		elapsed += roundElapsed
+
+		roundErrors := 0
+		timeline := make([]Interval, 0)
+		for _, res := range results {
+			second := int(res.finishedAfter / time.Second)
+			if q.mode == ModeSustained && second > q.lastSecond() {
+				// finished after the deadline, started before it
+				second = q.lastSecond()
+			}
+			for len(timeline) <= second {
+				timeline = append(timeline, Interval{})
+			}
+			timeline[second].Requests++
+			if res.retryAfter > step.RetryAfter {
+				step.RetryAfter = res.retryAfter
+			}
+			if res.errorClass != "" {
+				step.ErrorClasses[res.errorClass]++
+				timeline[second].Errors++
+				roundErrors++
+			}
+			if res.done {
+				latencies = append(latencies, res.latency)
+			}
+		}
+		step.Timeline = append(step.Timeline, timeline)
+		step.Requests += len(results)
+		step.FailedRequests += roundErrors
+		if q.acceptableErrorRate(roundErrors, len(results)) {
+			step.PassedRounds++
+		}
+	}
+	q.releaseConnections(url.attempts)
+	if url.ctx.Err() != nil {
+		// the step is not finished
+		q.drop(url)
+		return
+	}
+
+	step.Latency = NewLatencyStats(latencies)
+	if elapsed > 0 {
+		step.Throughput = float64(step.Requests) / elapsed.Seconds()
+	}
+	if !q.acceptableErrorRate(step.FailedRequests, step.Requests) {
+		// strategies compare errors with the step concurrency
+		step.Errors = StepErrors(step.Attempts, step.Requests, step.FailedRequests)
+	}
+	prevState := url.servedState()
+	url.errors = step.Errors
+	url.Steps = append(url.Steps, step)
+	if !q.next(url, prevState) {
+		return
+	}
 
-	cache.GetCache().Set(url.Url, url, url.ttl)
+	if !url.revalidate {
+		cacheUrl(url)
+	}
 	time.Sleep(20 * time.Millisecond)
-	if url.state == stateUrlInProgress {
-		q.pushForced(url)
+	q.pushForced(url)
+}
+
+// next chooses the next step of the url and publishes the done one,
+// the measured url is cached and false is returned.
+func (q *overloadQueue) next(url *Url, prevState string) bool {
+	url.state, url.Count, url.attempts = q.nextStep(url)
+	if url.state == StateUrlReady {
+		url.capByCrawlDelay()
+	}
+	if l := len(url.Steps); l > 0 {
+		step := url.Steps[l-1]
+		events.publish(&StepEvent{
+			Time:            time.Now(),
+			Host:            url.Host,
+			Urls:            url.Urls,
+			Concurrency:     step.Attempts,
+			Errors:          step.Errors,
+			FailedRequests:  step.FailedRequests,
+			Requests:        step.Requests,
+			ErrorClasses:    step.ErrorClasses,
+			Latency:         step.Latency,
+			Timeline:        step.Timeline,
+			PrevState:       prevState,
+			State:           url.state,
+			Count:           url.Count,
+			NextConcurrency: url.attempts,
+		})
 	}
+	if url.state != StateUrlInProgress {
+		url.MeasuredAt = time.Now()
+		// the result goes first, the url without a lease is orphaned
+		cacheUrl(url)
+		q.deactivate(url)
+		log.Printf(
+			"%s tested on %d connections and has %d errors, p95 latency %s",
+			url.Host,
+			url.Count,
+			url.errors,
+			url.lastLatency().P95,
+		)
+		if limitedAt, retryAfter := url.rateLimit(); limitedAt > 0 {
+			log.Printf("%s rate limited at %d, retry after %s", url.Host, limitedAt, retryAfter)
+		}
+		return false
+	}
+	url.errors = -1
+
+	return true
 }
 
-func (q *overloadQueue) push(url *Url) {
-	if cache.GetCache().Exists(url.Url) {
-		return
+// lastSecond is the last second of a sustained round.
+func (q *overloadQueue) lastSecond() int {
+	return int((q.sustainDuration+time.Second-1)/time.Second) - 1
+}
+
+func (q *overloadQueue) acceptableErrorRate(errs int, total int) bool {
+	return float64(errs)*100 <= q.stepMaxErrorRate*float64(total)
+}
+
+// push queues the url unless the host is cached or leased by another replica,
+// an orphaned url in progress is replaced. A revalidated url keeps the cached
+// result until it is measured.
+func (q *overloadQueue) push(url *Url) bool {
+	q.mxUrls.Lock()
+	if cached, err := q.getUrl(url.Host); err == nil && !url.revalidate && !q.orphaned(cached) {
+		q.mxUrls.Unlock()
+		return false
+	}
+	if !cache.GetCache().SetIfNotExists(leaseCacheKey(url.Host), q.replica, leaseTtl) {
+		q.mxUrls.Unlock()
+		return false
+	}
+	url.ctx, url.cancel = context.WithCancel(q.ctx)
+	q.active[url.Host] = url
+	if !url.revalidate {
+		cacheUrl(url)
+	}
+	q.urls = append(q.urls, url)
+	q.mxUrls.Unlock()
+	log.Printf("host pushed to queue: %s %v", url.Host, url.Urls)
+
+	return true
+}
+
+// orphaned reports whether the url is in progress but nobody measures it:
+// the replica holding the lease has been stopped or restarted.
+func (q *overloadQueue) orphaned(url *Url) bool {
+	return url.state == StateUrlInProgress && !q.leased(url.Host)
+}
+
+// leased reports whether the host is measured by a replica.
+func (q *overloadQueue) leased(host string) bool {
+	return cache.GetCache().Exists(leaseCacheKey(host))
+}
+
+func (q *overloadQueue) deactivate(url *Url) {
+	defer q.mxUrls.Unlock()
+	q.mxUrls.Lock()
+	if q.active[url.Host] == url {
+		delete(q.active, url.Host)
+		_ = cache.GetCache().Delete(leaseCacheKey(url.Host))
+	}
+	url.cancel()
+}
+
+// drop forgets the cancelled url, its host is measured again by the next request.
+// A revalidated url keeps the stale result.
+func (q *overloadQueue) drop(url *Url) {
+	defer q.mxUrls.Unlock()
+	q.mxUrls.Lock()
+	if q.active[url.Host] == url {
+		delete(q.active, url.Host)
+		if !url.revalidate {
+			_ = cache.GetCache().Delete(url.cacheKey())
+		}
+		_ = cache.GetCache().Delete(leaseCacheKey(url.Host))
+	}
+	log.Printf("%s is not measured anymore: nobody waits for it", url.Host)
+}
+
+func (q *overloadQueue) addWaiters(sites *dataProvider.HostsToCheck, n int) {
+	defer q.mxUrls.Unlock()
+	q.mxUrls.Lock()
+	for host := range sites.Items {
+		if q.waiters[host] += n; q.waiters[host] <= 0 {
+			delete(q.waiters, host)
+		}
+	}
+}
+
+// cancelUnwaited stops the workers of the hosts no Benchmark call waits for.
+func (q *overloadQueue) cancelUnwaited(sites *dataProvider.HostsToCheck) {
+	defer q.mxUrls.Unlock()
+	q.mxUrls.Lock()
+	for host := range sites.Items {
+		if url, ok := q.active[host]; ok && q.waiters[host] == 0 {
+			url.cancel()
+		}
 	}
-	cache.GetCache().Set(url.Url, url, url.ttl)
-	q.pushForced(url)
-	log.Printf("url pushed to queue: %v", url)
 }
 
 func (q *overloadQueue) pushForced(url *Url) {
@@ -187,9 +393,59 @@ func (q *overloadQueue) pushForced(url *Url) {
 	q.urls = append(q.urls, url)
 }
 
-func (q *overloadQueue) loadUrl(url string, errorsCount *int32, wg *sync.WaitGroup) {
+type loadResult struct {
+	done          bool
+	latency       time.Duration
+	errorClass    string
+	retryAfter    time.Duration
+	finishedAfter time.Duration
+}
+
+// runRound loads the urls of a host on the given concurrency: once per
+// connection in the burst mode, or continuously for the configured duration
+// in the sustained mode. Requests are spread across the urls round-robin.
+func (q *overloadQueue) runRound(ctx context.Context, urls []string, concurrency int) (results []loadResult, elapsed time.Duration) {
+	start := time.Now()
+	if q.mode == ModeBurst {
+		results = make([]loadResult, concurrency)
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(url string, res *loadResult) {
+				defer wg.Done()
+				q.loadUrl(url, res, start)
+			}(urls[i%len(urls)], &results[i])
+		}
+		wg.Wait()
+
+		return results, time.Since(start)
+	}
+
+	deadline := start.Add(q.sustainDuration)
+	workerResults := make([][]loadResult, concurrency)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := i; time.Now().Before(deadline) && ctx.Err() == nil; n++ {
+				res := loadResult{}
+				q.loadUrl(urls[n%len(urls)], &res, start)
+				workerResults[i] = append(workerResults[i], res)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, wr := range workerResults {
+		results = append(results, wr...)
+	}
+
+	return results, time.Since(start)
+}
+
+func (q *overloadQueue) loadUrl(url string, res *loadResult, roundStart time.Time) {
 	defer func() {
-		wg.Done()
+		res.finishedAfter = time.Since(roundStart)
 	}()
 
 	req := fasthttp.AcquireRequest()
@@ -198,31 +454,47 @@ func (q *overloadQueue) loadUrl(url string, errorsCount *int32, wg *sync.WaitGro
 		fasthttp.ReleaseRequest(req)
 	}()
 	req.SetRequestURI(url)
+	if q.userAgent != "" {
+		req.Header.SetUserAgent(q.userAgent)
+	}
 	resp := fasthttp.AcquireResponse()
 
 	defer fasthttp.ReleaseResponse(resp)
 
+	start := time.Now()
+	// http -> https and bare domain -> www redirects are followed, the latency includes them
 	err := (&fasthttp.Client{
 		ReadBufferSize: 1 << 20,
 		ReadTimeout:    15 * time.Second,
-	}).Do(req, resp)
+	}).DoRedirects(req, resp, maxRedirects)
 	if err != nil {
-		fmt.Printf("ERROR: %s: %s\n", url, err)
+		res.errorClass = ClassifyTransportError(err)
+		fmt.Printf("ERROR: %s: %s (%s)\n", url, err, res.errorClass)
 		return
 	}
 
-	if resp.StatusCode() != fasthttp.StatusOK {
-		// log.Printf("ERROR: http status %d %s", resp.StatusCode, url)
-		atomic.AddInt32(errorsCount, 1)
-	} else {
+	res.errorClass = ClassifyStatus(resp.StatusCode())
+	var body []byte
+	// the body is read only to look for captcha markers
+	if len(q.rateLimitMarkers) > 0 && mayBeChallenge(resp.StatusCode()) {
 		contentEncoding := resp.Header.Peek("Content-Encoding")
 		if bytes.EqualFold(contentEncoding, []byte("gzip")) {
 			fmt.Println("Unzipping...")
-			_, _ = resp.BodyGunzip()
+			body, _ = resp.BodyGunzip()
 		} else {
-			_ = resp.Body()
+			body = resp.Body()
 		}
 	}
+	if limited, retryAfter := DetectRateLimit(resp, body, q.rateLimitMarkers); limited {
+		res.errorClass = ErrorClassRateLimited
+		res.retryAfter = retryAfter
+	}
+
+	res.done = true
+	res.latency = time.Since(start)
+	if res.errorClass == "" && res.latency > q.responseTimeout {
+		res.errorClass = ErrorClassSlow
+	}
 }
 
 func (q *overloadQueue) _pusher(ctx context.Context) {
@@ -246,106 +518,69 @@ func (q *overloadQueue) _pusher(ctx context.Context) {
 	}
 }
 
-func (q *overloadQueue) getUrl(url string) (*Url, error) {
-	c := cache.GetCache()
-	if !c.Exists(url) {
-		return nil, cache.ErrNotExists
-	}
-	c.RLock()
-	defer c.RUnlock()
-	cachedUrl, err := c.GetRaw(url)
-	if err != nil {
-		return nil, err
+// _leaser prolongs the leases of the queued and running urls.
+func (q *overloadQueue) _leaser(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(leaseTtl / 3):
+			// the round trips of a shared cache are made without the lock
+			q.mxUrls.Lock()
+			hosts := make([]string, 0, len(q.active))
+			for host := range q.active {
+				hosts = append(hosts, host)
+			}
+			q.mxUrls.Unlock()
+			for _, host := range hosts {
+				if !q.renewLease(host) {
+					log.Printf("ERROR: lease of %s is not renewed\n", host)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
-
-	return &Url{
-		Url:   cachedUrl.(*Url).Url,
-		Count: cachedUrl.(*Url).Count,
-	}, nil
 }
 
-func (q *overloadQueue) nextStep(url *Url) (nextState string, nextCount int, nextAttempts int) {
-	switch q.method {
-	case methodSimple:
-		return q.nextStepSimple(url.Count, url.attempts, url.errors)
-	case methodStrong:
-		return q.nextStepStrong(url.Count, url.attempts, url.errors)
+// renewLease prolongs the lease of the host and reports whether it is kept,
+// the lease of a host deactivated meanwhile is dropped.
+func (q *overloadQueue) renewLease(host string) bool {
+	cache.GetCache().SetPinned(leaseCacheKey(host), q.replica, leaseTtl)
+	q.mxUrls.Lock()
+	_, active := q.active[host]
+	q.mxUrls.Unlock()
+	if !active {
+		_ = cache.GetCache().Delete(leaseCacheKey(host))
+		return true
 	}
-	return
+	return q.leased(host)
 }
 
-func (q *overloadQueue) nextStepSimple(count int, attempts int, errs int) (nextState string, nextCount int, nextAttempts int) {
-	nextAttempts = 0
-	nextState = stateUrlInProgress
-	if count == 0 && attempts == 0 {
-		nextAttempts = q.initConnectionsCount
-	} else {
-		if errs == attempts {
-			if count != q.initConnectionsCount {
-				nextState, nextCount, nextAttempts = stateUrlReady, count, 0
-			} else {
-				nextState, nextCount, nextAttempts = stateUrlFailed, 0, 0
-			}
-			return
-		}
-		if errs > 0 {
-			nextCount = attempts - errs
-			if count < 0 {
-				nextCount = 0
-			}
-			nextState = stateUrlReady
-		} else {
-			nextCount = attempts
-			nextAttempts = attempts * 2
-			if nextCount >= q.maxLimit {
-				nextState = stateUrlReady
-			}
-			if attempts > q.maxConnections {
-				nextAttempts = q.maxConnections
-			}
-		}
-	}
-
-	return
+func (q *overloadQueue) getUrl(host string) (*Url, error) {
+	return getCachedUrl(host)
 }
 
-func (q *overloadQueue) nextStepStrong(count int, attempts int, errs int) (nextState string, nextCount int, nextAttempts int) {
-	if count == 0 && attempts == 0 {
-		return stateUrlInProgress, q.initConnectionsCount, q.initConnectionsCount * 2
-	}
-	if errs == 0 {
-		return stateUrlInProgress, attempts, int(float32(attempts) * 1.5)
-	}
-	if errs > count {
-		nextAttempts = attempts*3/4 - 1
-		if count > nextAttempts {
-			count = nextAttempts
-		}
-		if count <= 0 {
-			return stateUrlFailed, 0, 0
-		}
-		return stateUrlInProgress, count, nextAttempts
-	}
-	return stateUrlReady, attempts - errs, 0
+func (q *overloadQueue) nextStep(url *Url) (nextState string, nextCount int, nextAttempts int) {
+	return q.strategy.NextStep(&StepHistory{
+		Host:     url.Host,
+		Url:      url.Url,
+		Count:    url.Count,
+		Attempts: url.attempts,
+		Errors:   url.errors,
+		Steps:    url.Steps,
+		Limits: StrategyLimits{
+			InitConnections: q.initConnectionsCount,
+			MaxLimit:        q.maxLimit,
+			MaxConnections:  q.maxConnections,
+			BisectTolerance: q.bisectTolerance,
+		},
+	})
 }
 
 var overloadBg *overloadQueue
 var overloadBgOnce sync.Once
 
-func (o overload) StartBackground(
-	workersCount int,
-	initConnectionsCount int,
-	maxLimit int,
-	maxConnections int,
-	method string,
-) error {
-	return getQueue().start(
-		workersCount,
-		initConnectionsCount,
-		maxLimit,
-		maxConnections,
-		method,
-	)
+func (o overload) StartBackground(options OverloadOptions) error {
+	return getQueue().start(options)
 }
 
 func (o overload) StopBackground() {
@@ -358,6 +593,10 @@ func getQueue() *overloadQueue {
 		overloadBg.state = stateQueueStopped
 		overloadBg.chUrls = make(chan *Url)
 		overloadBg.urls = make([]*Url, 0)
+		overloadBg.active = make(map[string]*Url)
+		overloadBg.waiters = make(map[string]int)
+		hostName, _ := os.Hostname()
+		overloadBg.replica = fmt.Sprintf("%s:%d:%d", hostName, os.Getpid(), time.Now().UnixNano())
 	})
 	return overloadBg
 }
@@ -0,0 +1,115 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/cache"
+	"time"
+)
+
+// urlSchemaVersion is increased on every incompatible change of urlRecord,
+// cached urls of other versions are measured again.
+const urlSchemaVersion = 2
+
+// urlRecord is the persistent and shared form of Url, the queue state of a url
+// in progress is not kept: such urls are measured again when their lease expires.
+type urlRecord struct {
+	Host       string
+	Url        string
+	Urls       []string
+	Count      int
+	Steps      []Step
+	Disallowed []string
+	CrawlDelay time.Duration
+	MeasuredAt time.Time
+	State      string
+	Ttl        time.Duration
+}
+
+type urlCodec struct{}
+
+func (urlCodec) Kind() string {
+	return "overload-url"
+}
+
+func (urlCodec) Version() int {
+	return urlSchemaVersion
+}
+
+func (urlCodec) Encode(value interface{}) ([]byte, error) {
+	u, ok := value.(*Url)
+	if !ok {
+		return nil, fmt.Errorf("url codec: unexpected value %T", value)
+	}
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(&urlRecord{
+		Host:       u.Host,
+		Url:        u.Url,
+		Urls:       u.Urls,
+		Count:      u.Count,
+		Steps:      u.Steps,
+		Disallowed: u.Disallowed,
+		CrawlDelay: u.CrawlDelay,
+		MeasuredAt: u.MeasuredAt,
+		State:      u.state,
+		Ttl:        u.ttl,
+	})
+	return buf.Bytes(), err
+}
+
+func (urlCodec) Decode(data []byte) (interface{}, error) {
+	r := urlRecord{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	return &Url{
+		Host:       r.Host,
+		Url:        r.Url,
+		Urls:       r.Urls,
+		Count:      r.Count,
+		Steps:      r.Steps,
+		Disallowed: r.Disallowed,
+		CrawlDelay: r.CrawlDelay,
+		MeasuredAt: r.MeasuredAt,
+		state:      r.State,
+		ttl:        r.Ttl,
+	}, nil
+}
+
+// cacheUrl saves the current state of the url, it is kept
+// for the stale window after its ttl. A url in progress is pinned:
+// the queue and the waiting requests rely on it.
+func cacheUrl(url *Url) {
+	set := cache.GetCache().SetEntry
+	if url.state == StateUrlInProgress {
+		set = cache.GetCache().SetPinnedEntry
+	}
+	if err := set(url.cacheKey(), urlCodec{}, url, url.ttl+getQueue().staleTtl); err != nil {
+		log.Printf("ERROR: cache %s: %s\n", url.Host, err.Error())
+	}
+}
+
+// getCachedUrl returns a copy of the cached url, expired urls
+// and urls of an outdated schema do not exist.
+func getCachedUrl(host string) (*Url, error) {
+	v, err := cache.GetCache().GetEntry(overloadCacheKey(host), urlCodec{})
+	switch {
+	case err == cache.ErrExpired:
+		return nil, cache.ErrNotExists
+	case errors.Is(err, cache.ErrSchemaVersion) || errors.Is(err, cache.ErrUnexpectedKind):
+		log.Printf("cached %s rejected: %s", host, err.Error())
+		return nil, cache.ErrNotExists
+	case err != nil:
+		return nil, err
+	}
+	url, ok := v.(*Url)
+	if !ok {
+		return nil, cache.ErrUnexpectedKind
+	}
+
+	return url, nil
+}
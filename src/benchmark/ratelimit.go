@@ -0,0 +1,57 @@
+package benchmark
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+// DetectRateLimit reports whether the response is a rate limit answer:
+// 429, 503 with Retry-After or a challenge page with one of the markers
+// in the body. Markers are expected in lower case.
+func DetectRateLimit(resp *fasthttp.Response, body []byte, markers [][]byte) (limited bool, retryAfter time.Duration) {
+	retryAfter = ParseRetryAfter(resp.Header.Peek("Retry-After"))
+	status := resp.StatusCode()
+	switch {
+	case status == fasthttp.StatusTooManyRequests:
+		return true, retryAfter
+	case status == fasthttp.StatusServiceUnavailable && len(resp.Header.Peek("Retry-After")) > 0:
+		return true, retryAfter
+	case mayBeChallenge(status):
+		lowerBody := bytes.ToLower(body)
+		for _, marker := range markers {
+			if bytes.Contains(lowerBody, marker) {
+				return true, retryAfter
+			}
+		}
+	}
+	return false, 0
+}
+
+// mayBeChallenge reports whether a response of the status may be a captcha
+// or an anti-bot page: they are served as successful, forbidden or unavailable.
+func mayBeChallenge(status int) bool {
+	return (status >= 200 && status < 300) ||
+		status == fasthttp.StatusForbidden ||
+		status == fasthttp.StatusServiceUnavailable
+}
+
+// ParseRetryAfter accepts both delay seconds and HTTP date values.
+func ParseRetryAfter(value []byte) time.Duration {
+	if len(value) == 0 {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(string(bytes.TrimSpace(value))); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := fasthttp.ParseHTTPDate(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d.Round(time.Second)
+		}
+	}
+	return 0
+}
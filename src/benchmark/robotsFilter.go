@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/cache"
+	"lubyshev/go-site-benchmark/src/robots"
+	"net/url"
+	"time"
+)
+
+const robotsCacheTtl = 600 * time.Second
+
+// robotsSchemaVersion is increased on every incompatible change of robotsRecord.
+const robotsSchemaVersion = 1
+
+// robotsEntry is the robots.txt of a site,
+// rules is nil when robots.txt is unreachable.
+type robotsEntry struct {
+	body  []byte
+	rules *robots.Robots
+}
+
+// robotsRecord is the cached form of robotsEntry: the rules are parsed again
+// from the body, so the cache does not depend on the parser internals.
+type robotsRecord struct {
+	Reachable bool
+	Body      []byte
+}
+
+type robotsCodec struct{}
+
+func (robotsCodec) Kind() string {
+	return "robots"
+}
+
+func (robotsCodec) Version() int {
+	return robotsSchemaVersion
+}
+
+func (robotsCodec) Encode(value interface{}) ([]byte, error) {
+	entry, ok := value.(*robotsEntry)
+	if !ok {
+		return nil, fmt.Errorf("robots codec: unexpected value %T", value)
+	}
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(&robotsRecord{Reachable: entry.rules != nil, Body: entry.body})
+	return buf.Bytes(), err
+}
+
+func (robotsCodec) Decode(data []byte) (interface{}, error) {
+	r := robotsRecord{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+		return nil, err
+	}
+	if !r.Reachable {
+		return &robotsEntry{}, nil
+	}
+	return &robotsEntry{body: r.Body, rules: robots.Parse(r.Body)}, nil
+}
+
+// filterRobots splits the urls by the robots.txt rules of their sites
+// for the configured user agent and returns the longest Crawl-delay.
+// Urls of sites with unreachable robots.txt are disallowed.
+func (q *overloadQueue) filterRobots(ctx context.Context, urls []string) (allowed []string, disallowed []string, crawlDelay time.Duration, err error) {
+	allowed = make([]string, 0, len(urls))
+	for _, rawUrl := range urls {
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			disallowed = append(disallowed, rawUrl)
+			continue
+		}
+		entry, err := q.getRobots(ctx, u.Scheme+"://"+u.Host)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if entry.rules == nil || !entry.rules.Allowed(q.userAgent, u.RequestURI()) {
+			disallowed = append(disallowed, rawUrl)
+			continue
+		}
+		allowed = append(allowed, rawUrl)
+		if delay := entry.rules.CrawlDelay(q.userAgent); delay > crawlDelay {
+			crawlDelay = delay
+		}
+	}
+
+	return allowed, disallowed, crawlDelay, nil
+}
+
+// getRobots fails only when ctx is done.
+func (q *overloadQueue) getRobots(ctx context.Context, site string) (*robotsEntry, error) {
+	key := "robots::" + site
+	v, err := cache.GetCache().GetEntry(key, robotsCodec{})
+	if err == nil {
+		if entry, ok := v.(*robotsEntry); ok {
+			return entry, nil
+		}
+	}
+	if errors.Is(err, cache.ErrSchemaVersion) || errors.Is(err, cache.ErrUnexpectedKind) {
+		log.Printf("cached %s rejected: %s", key, err.Error())
+	}
+
+	entry := &robotsEntry{}
+	body, err := robots.FetchBody(ctx, site, q.userAgent)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("robots.txt of %s is unreachable: %s", site, err.Error())
+	} else {
+		entry.body, entry.rules = body, robots.Parse(body)
+	}
+	if err = cache.GetCache().SetEntry(key, robotsCodec{}, entry, robotsCacheTtl); err != nil {
+		log.Printf("ERROR: cache %s: %s\n", key, err.Error())
+	}
+
+	return entry, nil
+}
+
+// crawlDelayLimit is the max number of connections keeping the request
+// rate within one request per Crawl-delay, at least one.
+func crawlDelayLimit(crawlDelay time.Duration, latency time.Duration) int {
+	if crawlDelay <= 0 {
+		return 0
+	}
+	limit := int(latency / crawlDelay)
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
@@ -0,0 +1,226 @@
+package benchmark
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+const (
+	StrategySimple = "simple"
+	StrategyStrong = "strong"
+	StrategyBisect = "bisect"
+)
+
+var (
+	ErrStrategyExists   = errors.New("strategy already registered")
+	ErrStrategyNotFound = errors.New("strategy not found")
+	ErrStrategyBuiltin  = errors.New("builtin strategy can not be unregistered")
+)
+
+type StrategyLimits struct {
+	InitConnections int
+	MaxLimit        int
+	MaxConnections  int
+	BisectTolerance int
+}
+
+// StepHistory describes the state of a Url after the last step:
+// Count is the current recommendation, Attempts and Errors belong to
+// the last step and Steps holds every step done so far.
+type StepHistory struct {
+	Host     string
+	Url      string
+	Count    int
+	Attempts int
+	Errors   int
+	Steps    []Step
+	Limits   StrategyLimits
+}
+
+// Strategy decides the next step of the overload test. It returns
+// StateUrlInProgress with the concurrency of the next step, or
+// StateUrlReady / StateUrlFailed with the final recommendation.
+type Strategy interface {
+	NextStep(history *StepHistory) (nextState string, nextCount int, nextAttempts int)
+}
+
+type StrategyFunc func(history *StepHistory) (nextState string, nextCount int, nextAttempts int)
+
+func (f StrategyFunc) NextStep(history *StepHistory) (nextState string, nextCount int, nextAttempts int) {
+	return f(history)
+}
+
+var (
+	strategies   = make(map[string]Strategy)
+	strategiesMx sync.RWMutex
+)
+
+func init() {
+	_ = RegisterStrategy(StrategySimple, StrategyFunc(nextStepSimple))
+	_ = RegisterStrategy(StrategyStrong, StrategyFunc(nextStepStrong))
+	_ = RegisterStrategy(StrategyBisect, StrategyFunc(nextStepBisect))
+}
+
+func RegisterStrategy(name string, strategy Strategy) error {
+	defer strategiesMx.Unlock()
+	strategiesMx.Lock()
+	if _, ok := strategies[name]; ok {
+		return ErrStrategyExists
+	}
+	strategies[name] = strategy
+
+	return nil
+}
+
+// UnregisterStrategy removes a custom strategy, e.g. registered by a test.
+func UnregisterStrategy(name string) error {
+	defer strategiesMx.Unlock()
+	strategiesMx.Lock()
+	switch name {
+	case StrategySimple, StrategyStrong, StrategyBisect:
+		return ErrStrategyBuiltin
+	}
+	if _, ok := strategies[name]; !ok {
+		return ErrStrategyNotFound
+	}
+	delete(strategies, name)
+
+	return nil
+}
+
+func GetStrategy(name string) (Strategy, error) {
+	defer strategiesMx.RUnlock()
+	strategiesMx.RLock()
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, ErrStrategyNotFound
+	}
+
+	return strategy, nil
+}
+
+func GetStrategyNames() []string {
+	defer strategiesMx.RUnlock()
+	strategiesMx.RLock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func nextStepSimple(h *StepHistory) (nextState string, nextCount int, nextAttempts int) {
+	count, attempts, errs := h.Count, h.Attempts, h.Errors
+	nextAttempts = 0
+	nextState = StateUrlInProgress
+	if count == 0 && attempts == 0 {
+		nextAttempts = h.Limits.InitConnections
+	} else {
+		if errs == attempts {
+			if count != h.Limits.InitConnections {
+				nextState, nextCount, nextAttempts = StateUrlReady, count, 0
+			} else {
+				nextState, nextCount, nextAttempts = StateUrlFailed, 0, 0
+			}
+			return
+		}
+		if errs > 0 {
+			nextCount = attempts - errs
+			if nextCount < 0 {
+				nextCount = 0
+			}
+			nextState = StateUrlReady
+		} else {
+			nextCount = attempts
+			if nextCount >= h.Limits.MaxLimit {
+				nextState = StateUrlReady
+				return
+			}
+			nextAttempts = capAttempts(attempts*2, h.Limits)
+			if nextAttempts <= attempts {
+				nextState, nextAttempts = StateUrlReady, 0
+			}
+		}
+	}
+
+	return
+}
+
+func nextStepStrong(h *StepHistory) (nextState string, nextCount int, nextAttempts int) {
+	count, attempts, errs := h.Count, h.Attempts, h.Errors
+	if count == 0 && attempts == 0 {
+		return StateUrlInProgress, h.Limits.InitConnections, h.Limits.InitConnections * 2
+	}
+	if errs == 0 {
+		nextAttempts = capAttempts(int(float32(attempts)*1.5), h.Limits)
+		if nextAttempts <= attempts {
+			return StateUrlReady, attempts, 0
+		}
+		return StateUrlInProgress, attempts, nextAttempts
+	}
+	if errs > count {
+		nextAttempts = attempts*3/4 - 1
+		if count > nextAttempts {
+			count = nextAttempts
+		}
+		if count <= 0 {
+			return StateUrlFailed, 0, 0
+		}
+		return StateUrlInProgress, count, nextAttempts
+	}
+	return StateUrlReady, attempts - errs, 0
+}
+
+// nextStepBisect doubles the count until the first failing step, then bisects
+// between the highest passed and the lowest failed count.
+func nextStepBisect(h *StepHistory) (nextState string, nextCount int, nextAttempts int) {
+	if len(h.Steps) == 0 {
+		return StateUrlInProgress, 0, h.Limits.InitConnections
+	}
+	passed, failed := 0, 0
+	for _, step := range h.Steps {
+		if step.Errors == 0 && step.Attempts > passed {
+			passed = step.Attempts
+		}
+	}
+	for _, step := range h.Steps {
+		if step.Errors > 0 && step.Attempts > passed && (failed == 0 || step.Attempts < failed) {
+			failed = step.Attempts
+		}
+	}
+
+	if failed == 0 {
+		if passed >= h.Limits.MaxLimit {
+			return StateUrlReady, passed, 0
+		}
+		nextAttempts = capAttempts(passed*2, h.Limits)
+		if nextAttempts <= passed {
+			return StateUrlReady, passed, 0
+		}
+		return StateUrlInProgress, passed, nextAttempts
+	}
+
+	if failed-passed <= h.Limits.BisectTolerance {
+		if passed == 0 {
+			return StateUrlFailed, 0, 0
+		}
+		return StateUrlReady, passed, 0
+	}
+
+	return StateUrlInProgress, passed, (passed + failed) / 2
+}
+
+// capAttempts keeps the next step within the recommendation limit
+// and the connections the queue can allocate.
+func capAttempts(attempts int, limits StrategyLimits) int {
+	if attempts > limits.MaxLimit {
+		attempts = limits.MaxLimit
+	}
+	if attempts > limits.MaxConnections {
+		attempts = limits.MaxConnections
+	}
+	return attempts
+}
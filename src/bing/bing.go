@@ -0,0 +1,79 @@
+package bing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"lubyshev/go-site-benchmark/src/domain"
+	"lubyshev/go-site-benchmark/src/serp"
+	"net/url"
+	"strings"
+)
+
+const searchBingTemplate = "https://www.bing.com/search?count=50&q=%s"
+
+// engine recognizes the challenge page bing shows to robots
+var engine = &serp.Engine{
+	Name:         "bing",
+	CaptchaPaths: []string{"/challenge/"},
+	Markers:      [][]byte{[]byte("b_captcha"), []byte("/challenge/verify")},
+}
+
+func GetBingSearchResult(ctx context.Context, searchPhrase string) (res *serp.ResponseStruct, err error) {
+	body, err := engine.Fetch(ctx, fmt.Sprintf(searchBingTemplate, url.QueryEscape(searchPhrase)))
+	if err != nil {
+		return nil, err
+	}
+	return ParseBingResponse(body)
+}
+
+// ParseBingResponse takes organic results, "/ck/a" click tracking links
+// are resolved to the target urls.
+func ParseBingResponse(response []byte) (sites *serp.ResponseStruct, err error) {
+	res := serp.ResponseStruct{Items: make([]serp.ResponseItem, 0)}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response))
+	if err != nil {
+		err = fmt.Errorf("can't create parser for body: %v", err)
+		return
+	}
+	doc.Find("#b_results li.b_algo").Each(func(i int, selection *goquery.Selection) {
+		link := selection.Find("h2 a").First()
+		urlStr, ok := link.Attr("href")
+		if !ok {
+			return
+		}
+		urlStr = resolveClickUrl(urlStr)
+
+		u, err := url.Parse(urlStr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		host := domain.GetRootDomain(u.Hostname())
+		if host == "bing.com" {
+			return
+		}
+
+		res.Items = append(res.Items, serp.ResponseItem{
+			Host: host,
+			Url:  urlStr,
+		})
+	})
+	return &res, nil
+}
+
+// resolveClickUrl decodes the "u" param of bing.com/ck/a links:
+// "a1" followed by the base64url encoded target.
+func resolveClickUrl(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil || !serp.IsDomainOf(u.Hostname(), "bing.com") || u.Path != "/ck/a" {
+		return urlStr
+	}
+	target := strings.TrimPrefix(u.Query().Get("u"), "a1")
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(target, "="))
+	if err != nil {
+		return urlStr
+	}
+	return string(decoded)
+}
@@ -9,9 +9,10 @@ import (
 )
 
 var (
-	ErrNotExists          = errors.New("cache value does not exists")
-	ErrExpired            = errors.New("cache value has been expired")
-	ErrBgAlreadyStarted   = errors.New("cache background already started")
+	ErrNotExists        = errors.New("cache value does not exists")
+	ErrExpired          = errors.New("cache value has been expired")
+	ErrBgAlreadyStarted = errors.New("cache background already started")
+	ErrNotBounded       = errors.New("cache storage can not be bounded")
 )
 
 var itemsPool = sync.Pool{
@@ -21,10 +22,12 @@ var itemsPool = sync.Pool{
 type Item struct {
 	value interface{}
 	ttl   time.Time
+	// a pinned item is not evicted over the limits, it still expires
+	pinned bool
 }
 
 type Cache struct {
-	items   map[string]*Item
+	storage Storage
 	mx      sync.RWMutex
 	started bool
 }
@@ -35,58 +38,144 @@ var once sync.Once
 
 func GetCache() *Cache {
 	once.Do(func() {
-		cache = new(Cache)
-		cache.items = make(map[string]*Item, 0)
+		cache = New(NewMemoryStorage())
 	})
 	return cache
 }
 
-func (c *Cache) Set(name string, value interface{}, ttl time.Duration) *Cache {
+func New(storage Storage) *Cache {
+	return &Cache{storage: storage}
+}
+
+// SetStorage replaces the storage, items of the previous one are dropped.
+func (c *Cache) SetStorage(storage Storage) error {
 	defer c.mx.Unlock()
 	c.mx.Lock()
-	if _, ok := c.items[name]; !ok {
-		c.items[name] = itemsPool.Get().(*Item)
-	}
-	c.items[name].value = value
-	c.items[name].ttl = time.Now().Add(ttl)
+	err := c.storage.Close()
+	c.storage = storage
 
-	return c
+	return err
 }
 
-func (c *Cache) Delete(name string) error {
+// SetLimits bounds the items kept in memory by the storage,
+// the least recently used items are evicted.
+func (c *Cache) SetLimits(limits Limits) error {
 	defer c.mx.Unlock()
 	c.mx.Lock()
-	if _, ok := c.items[name]; !ok {
-		return ErrNotExists
+	storage, ok := c.storage.(BoundedStorage)
+	if !ok {
+		return ErrNotBounded
 	}
-	c.items[name].value = nil
-	itemsPool.Put(c.items[name])
-	c.items[name] = nil
-	delete(c.items, name)
+	storage.SetLimits(limits)
 
 	return nil
 }
 
-func (c *Cache) Exists(name string) bool {
+// Stats returns the usage and evictions of the storage memory.
+func (c *Cache) Stats() Stats {
 	defer c.mx.RUnlock()
 	c.mx.RLock()
-	i, ok := c.items[name]
-	return ok && !i.ttl.Before(time.Now())
+	if storage, ok := c.storage.(BoundedStorage); ok {
+		return storage.Stats()
+	}
+	return Stats{Items: c.storage.Len()}
 }
 
-func (c *Cache) Get(name string) (interface{}, error) {
-	var item *Item
-	var ok bool
-	defer c.mx.RUnlock()
+func (c *Cache) Close() error {
+	defer c.mx.Unlock()
+	c.mx.Lock()
+	return c.storage.Close()
+}
+
+// lock serializes the changes of a local storage. A shared storage locks
+// itself, so its round trips do not block the other callers.
+func (c *Cache) lock() (Storage, func()) {
+	c.mx.Lock()
+	storage := c.storage
+	if _, ok := storage.(SharedStorage); ok {
+		c.mx.Unlock()
+		return storage, func() {}
+	}
+	return storage, c.mx.Unlock
+}
+
+func (c *Cache) rlock() (Storage, func()) {
 	c.mx.RLock()
-	if item, ok = c.items[name]; !ok {
-		return nil, ErrNotExists
+	storage := c.storage
+	if _, ok := storage.(SharedStorage); ok {
+		c.mx.RUnlock()
+		return storage, func() {}
 	}
-	if item.ttl.Before(time.Now()) {
-		return nil, ErrExpired
+	return storage, c.mx.RUnlock
+}
+
+func (c *Cache) Set(name string, value interface{}, ttl time.Duration) *Cache {
+	c.set(name, value, ttl, false)
+	return c
+}
+
+// SetPinned sets the value that is not evicted over the limits of the storage,
+// e.g. a work in progress. Setting the name again unpins it.
+func (c *Cache) SetPinned(name string, value interface{}, ttl time.Duration) *Cache {
+	c.set(name, value, ttl, true)
+	return c
+}
+
+func (c *Cache) set(name string, value interface{}, ttl time.Duration, pinned bool) {
+	storage, unlock := c.lock()
+	defer unlock()
+	// a new item every time: a shared storage would make a round trip to find the old one
+	item := itemsPool.Get().(*Item)
+	item.value = value
+	item.ttl = time.Now().Add(ttl)
+	item.pinned = pinned
+	storage.Set(name, item)
+}
+
+// SetIfNotExists sets the value unless the name has an unexpired one, the result
+// reports whether the value has been set. With a shared storage it is atomic
+// across the service replicas. The value is a marker, so it is pinned.
+func (c *Cache) SetIfNotExists(name string, value interface{}, ttl time.Duration) bool {
+	storage, unlock := c.lock()
+	defer unlock()
+	item := itemsPool.Get().(*Item)
+	item.value = value
+	item.ttl = time.Now().Add(ttl)
+	item.pinned = true
+	if !storage.SetNX(name, item) {
+		item.value = nil
+		itemsPool.Put(item)
+		return false
 	}
 
-	return c.items[name].value, nil
+	return true
+}
+
+func (c *Cache) Delete(name string) error {
+	storage, unlock := c.lock()
+	defer unlock()
+	item, ok := storage.Get(name)
+	if !ok {
+		return ErrNotExists
+	}
+	storage.Delete(name)
+	item.value = nil
+	itemsPool.Put(item)
+
+	return nil
+}
+
+func (c *Cache) Exists(name string) bool {
+	storage, unlock := c.rlock()
+	defer unlock()
+	i, ok := storage.Get(name)
+	return ok && !i.ttl.Before(time.Now())
+}
+
+func (c *Cache) Get(name string) (interface{}, error) {
+	storage, unlock := c.rlock()
+	defer unlock()
+	return getItem(storage, name)
 }
 
 func (c *Cache) RLock() {
@@ -98,17 +187,19 @@ func (c *Cache) RUnlock() {
 }
 
 func (c *Cache) GetRaw(name string) (interface{}, error) {
-	var item *Item
-	var ok bool
+	return getItem(c.storage, name)
+}
 
-	if item, ok = c.items[name]; !ok {
+func getItem(storage Storage, name string) (interface{}, error) {
+	item, ok := storage.Get(name)
+	if !ok {
 		return nil, ErrNotExists
 	}
 	if item.ttl.Before(time.Now()) {
 		return nil, ErrExpired
 	}
 
-	return c.items[name].value, nil
+	return item.value, nil
 }
 
 func (c *Cache) StartBackground(ctx context.Context, frequency time.Duration, debug bool) error {
@@ -126,18 +217,31 @@ func (c *Cache) _garbageCollector(ctx context.Context, frequency time.Duration,
 		select {
 		case <-time.After(frequency):
 			c.mx.Lock()
-			counter := 0
-			for name, item := range c.items {
+			// no round trips to a shared storage under the lock
+			storage := c.storage
+			if shared, ok := storage.(SharedStorage); ok {
+				storage = shared.Local()
+			}
+			expired := make(map[string]*Item)
+			storage.Range(func(name string, item *Item) bool {
 				if item.ttl.Before(time.Now()) {
-					c.items[name].value = nil
-					itemsPool.Put(c.items[name])
-					c.items[name] = nil
-					delete(c.items, name)
-					counter++
+					expired[name] = item
 				}
+				return true
+			})
+			for name, item := range expired {
+				storage.Delete(name)
+				item.value = nil
+				itemsPool.Put(item)
 			}
+			overall := storage.Len()
 			c.mx.Unlock()
-			log.Printf("garbage collector: %d items overall, %d items deleted", len(c.items), counter)
+			log.Printf(
+				"garbage collector: %d items in memory, %d items deleted, %d items evicted since start",
+				overall,
+				len(expired),
+				c.Stats().Evictions,
+			)
 
 		case <-ctx.Done():
 			log.Printf("cache background stopped")
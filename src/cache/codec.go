@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSchemaVersion  = errors.New("cache value has an unsupported schema version")
+	ErrUnexpectedKind = errors.New("cache value has an unexpected kind")
+)
+
+func init() {
+	Register(&Entry{})
+}
+
+// Codec converts values of one kind to bytes and back. The version is
+// increased on every incompatible change of the encoding.
+type Codec interface {
+	Kind() string
+	Version() int
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// Entry is a value encoded by its codec, every storage keeps and shares it as is.
+type Entry struct {
+	Kind    string
+	Version int
+	Data    []byte
+}
+
+// SetEntry encodes the value by the codec and sets it.
+func (c *Cache) SetEntry(name string, codec Codec, value interface{}, ttl time.Duration) error {
+	return c.setEntry(name, codec, value, ttl, false)
+}
+
+// SetPinnedEntry encodes the value by the codec and sets it pinned, see SetPinned.
+func (c *Cache) SetPinnedEntry(name string, codec Codec, value interface{}, ttl time.Duration) error {
+	return c.setEntry(name, codec, value, ttl, true)
+}
+
+func (c *Cache) setEntry(name string, codec Codec, value interface{}, ttl time.Duration, pinned bool) error {
+	data, err := codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	c.set(name, &Entry{Kind: codec.Kind(), Version: codec.Version(), Data: data}, ttl, pinned)
+
+	return nil
+}
+
+// GetEntry decodes the value set by SetEntry. Values of another kind
+// or schema version are rejected.
+func (c *Cache) GetEntry(name string, codec Codec) (interface{}, error) {
+	v, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := v.(*Entry)
+	if !ok || entry.Kind != codec.Kind() {
+		return nil, ErrUnexpectedKind
+	}
+	if entry.Version != codec.Version() {
+		return nil, ErrSchemaVersion
+	}
+
+	return codec.Decode(entry.Data)
+}
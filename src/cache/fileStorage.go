@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// the log is compacted when it has this many records more than the live items
+const compactMinRecords = 1000
+
+// Register makes values of the type persistent in the file storage,
+// values of other types are kept in memory only.
+func Register(value interface{}) {
+	gob.Register(value)
+}
+
+type record struct {
+	Name    string
+	Value   interface{}
+	Expires time.Time
+	Deleted bool
+}
+
+// fileStorage keeps the items in memory and appends every change to a log file.
+// Every record is framed by its length and crc32, so a torn tail of the log
+// is detected and dropped on load. The log is compacted on load and when
+// it grows over the live items.
+type fileStorage struct {
+	*memoryStorage
+	fileName  string
+	file      *os.File
+	records   int
+	persisted map[string]struct{}
+}
+
+// NewFileStorage loads the unexpired items of the log file, the file
+// and its directory are created if missing.
+func NewFileStorage(fileName string) (Storage, error) {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return nil, err
+	}
+	s := &fileStorage{
+		memoryStorage: newMemoryStorage(),
+		fileName:      fileName,
+		persisted:     make(map[string]struct{}),
+	}
+	s.onEvict = s.forget
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	log.Printf("cache storage %s: %d items loaded", fileName, s.Len())
+
+	return s, nil
+}
+
+func (s *fileStorage) Set(name string, item *Item) {
+	s.memoryStorage.Set(name, item)
+	if err := s.append(&record{Name: name, Value: item.value, Expires: item.ttl}); err != nil {
+		if _, ok := s.persisted[name]; ok {
+			// forget the persisted value replaced by a memory only one
+			_ = s.append(&record{Name: name, Deleted: true})
+			delete(s.persisted, name)
+		}
+		return
+	}
+	s.persisted[name] = struct{}{}
+	if s.records > 2*len(s.persisted)+compactMinRecords {
+		if err := s.compact(); err != nil {
+			log.Printf("ERROR: cache storage compaction: %s\n", err.Error())
+		}
+	}
+}
+
+func (s *fileStorage) SetNX(name string, item *Item) bool {
+	if current, ok := s.peek(name); ok && !current.ttl.Before(time.Now()) {
+		return false
+	}
+	s.Set(name, item)
+	return true
+}
+
+func (s *fileStorage) Delete(name string) bool {
+	if !s.memoryStorage.Delete(name) {
+		return false
+	}
+	s.forget(name)
+	return true
+}
+
+// forget deletes the persisted value of the name.
+func (s *fileStorage) forget(name string) {
+	if _, ok := s.persisted[name]; ok {
+		delete(s.persisted, name)
+		_ = s.append(&record{Name: name, Deleted: true})
+	}
+}
+
+func (s *fileStorage) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// append writes the record, an error means the value is not persistent.
+func (s *fileStorage) append(r *record) error {
+	frame, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(frame); err != nil {
+		log.Printf("ERROR: cache storage write: %s\n", err.Error())
+		return err
+	}
+	s.records++
+	return nil
+}
+
+func (s *fileStorage) load() error {
+	f, err := os.Open(s.fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	now := time.Now()
+	r := bufio.NewReader(f)
+	header := make([]byte, 8)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			if err != io.EOF {
+				log.Printf("cache storage %s: truncated record dropped", s.fileName)
+			}
+			return nil
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err = io.ReadFull(r, payload); err != nil || crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			log.Printf("cache storage %s: corrupted tail dropped", s.fileName)
+			return nil
+		}
+		rec := record{}
+		if err = gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+			// e.g. a type not registered anymore
+			continue
+		}
+		if rec.Deleted || rec.Expires.Before(now) {
+			s.memoryStorage.Delete(rec.Name)
+			delete(s.persisted, rec.Name)
+			continue
+		}
+		s.memoryStorage.Set(rec.Name, &Item{value: rec.Value, ttl: rec.Expires})
+		s.persisted[rec.Name] = struct{}{}
+	}
+}
+
+// compact rewrites the log with the persisted items only.
+func (s *fileStorage) compact() error {
+	tmpName := s.fileName + ".tmp"
+	tmp, err := os.OpenFile(tmpName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	records := 0
+	for name := range s.persisted {
+		item, ok := s.peek(name)
+		if !ok {
+			delete(s.persisted, name)
+			continue
+		}
+		frame, err := encodeRecord(&record{Name: name, Value: item.value, Expires: item.ttl})
+		if err != nil {
+			delete(s.persisted, name)
+			continue
+		}
+		if _, err = w.Write(frame); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+		records++
+	}
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	if err = os.Rename(tmpName, s.fileName); err == nil {
+		s.records = records
+	}
+	// keep appending to the old log if the rename failed
+	file, openErr := os.OpenFile(s.fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return openErr
+	}
+	s.file = file
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func encodeRecord(r *record) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 8, 256))
+	if err := gob.NewEncoder(buf).Encode(r); err != nil {
+		return nil, err
+	}
+	frame := buf.Bytes()
+	payload := frame[8:]
+	if len(payload) > int(^uint32(0)) {
+		return nil, errors.New("cache record is too large")
+	}
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+
+	return frame, nil
+}
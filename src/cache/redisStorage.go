@@ -0,0 +1,390 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisTimeout = 5 * time.Second
+
+// redisPoolSize is the number of idle connections kept open.
+const redisPoolSize = 8
+
+// redisStorage shares the items between the service replicas through
+// a redis compatible server. Values of types not registered by Register
+// can not be shared, they are kept in memory of the replica.
+type redisStorage struct {
+	local    *memoryStorage
+	addr     string
+	password string
+	db       int
+	prefix   string
+	// idle connections, a caller takes one for a round trip
+	// or dials a new one when there is none
+	idle   chan *redisConn
+	mx     sync.Mutex
+	closed bool
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisStorage connects to the server, every key is prefixed by the prefix
+// so several services may share a database.
+func NewRedisStorage(addr string, password string, db int, prefix string) (Storage, error) {
+	s := &redisStorage{
+		local:    newMemoryStorage(),
+		addr:     addr,
+		password: password,
+		db:       db,
+		prefix:   prefix,
+		idle:     make(chan *redisConn, redisPoolSize),
+	}
+	if _, err := s.do("PING"); err != nil {
+		return nil, fmt.Errorf("cache storage %s: %w", addr, err)
+	}
+	log.Printf("cache storage redis://%s/%d connected", addr, db)
+
+	return s, nil
+}
+
+func (s *redisStorage) Get(name string) (*Item, bool) {
+	if item, ok := s.local.Get(name); ok {
+		return item, true
+	}
+	reply, err := s.do("GET", s.prefix+name)
+	if err != nil {
+		log.Printf("ERROR: cache storage get %s: %s\n", name, err.Error())
+		return nil, false
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, false
+	}
+	rec := record{}
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		// e.g. a type not registered by this replica
+		return nil, false
+	}
+
+	return &Item{value: rec.Value, ttl: rec.Expires}, true
+}
+
+func (s *redisStorage) Set(name string, item *Item) {
+	s.set(name, item, false)
+}
+
+func (s *redisStorage) SetNX(name string, item *Item) bool {
+	return s.set(name, item, true)
+}
+
+func (s *redisStorage) set(name string, item *Item, nx bool) bool {
+	data, err := encodeValue(&record{Name: name, Value: item.value, Expires: item.ttl})
+	if err != nil {
+		if !nx {
+			s.local.Set(name, item)
+		} else if !s.local.SetNX(name, item) {
+			return false
+		}
+		// forget the shared value replaced by a memory only one
+		_, _ = s.do("DEL", s.prefix+name)
+		return true
+	}
+	if nx {
+		if current, ok := s.local.Get(name); ok && !current.ttl.Before(time.Now()) {
+			return false
+		}
+	}
+	ttl := time.Until(item.ttl).Milliseconds()
+	if ttl <= 0 {
+		s.local.Delete(name)
+		_, _ = s.do("DEL", s.prefix+name)
+		return !nx
+	}
+	args := []string{"SET", s.prefix + name, string(data), "PX", strconv.FormatInt(ttl, 10)}
+	if nx {
+		args = append(args, "NX")
+	}
+	reply, err := s.do(args...)
+	if err != nil {
+		log.Printf("ERROR: cache storage set %s: %s\n", name, err.Error())
+		return false
+	}
+	s.local.Delete(name)
+
+	// SET NX replies nil when the key exists
+	return reply != nil
+}
+
+func (s *redisStorage) Delete(name string) bool {
+	deleted := s.local.Delete(name)
+	reply, err := s.do("DEL", s.prefix+name)
+	if err != nil {
+		log.Printf("ERROR: cache storage delete %s: %s\n", name, err.Error())
+		return deleted
+	}
+	n, _ := reply.(int64)
+
+	return deleted || n > 0
+}
+
+func (s *redisStorage) Range(f func(name string, item *Item) bool) {
+	next := true
+	s.local.Range(func(name string, item *Item) bool {
+		next = f(name, item)
+		return next
+	})
+	if !next {
+		return
+	}
+	s.scan(func(name string) bool {
+		if item, ok := s.Get(name); ok {
+			return f(name, item)
+		}
+		return true
+	})
+}
+
+func (s *redisStorage) Len() int {
+	n := s.local.Len()
+	s.scan(func(string) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (s *redisStorage) Close() error {
+	s.mx.Lock()
+	s.closed = true
+	s.mx.Unlock()
+	var err error
+	for {
+		select {
+		case c := <-s.idle:
+			if closeErr := c.conn.Close(); err == nil {
+				err = closeErr
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// Local returns the values kept in memory, the shared ones are expired
+// by the server.
+func (s *redisStorage) Local() Storage {
+	return s.local
+}
+
+// SetLimits bounds the values kept in memory, the memory of the server
+// is bounded by its own maxmemory policy.
+func (s *redisStorage) SetLimits(limits Limits) {
+	s.local.SetLimits(limits)
+}
+
+func (s *redisStorage) Stats() Stats {
+	return s.local.Stats()
+}
+
+// scan calls f for the names of the shared items until it returns false.
+func (s *redisStorage) scan(f func(name string) bool) {
+	cursor := "0"
+	for {
+		reply, err := s.do("SCAN", cursor, "MATCH", escapePattern(s.prefix)+"*", "COUNT", "100")
+		if err != nil {
+			log.Printf("ERROR: cache storage scan: %s\n", err.Error())
+			return
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return
+		}
+		next, _ := parts[0].([]byte)
+		keys, _ := parts[1].([]interface{})
+		for _, key := range keys {
+			if k, ok := key.([]byte); ok && !f(strings.TrimPrefix(string(k), s.prefix)) {
+				return
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return
+		}
+	}
+}
+
+// do sends the command and reads its reply over an idle connection,
+// a broken one is replaced by a new connection once.
+func (s *redisStorage) do(args ...string) (interface{}, error) {
+	c, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.roundTrip(args)
+	if _, ok := err.(redisError); err != nil && !ok {
+		_ = c.conn.Close()
+		if c, err = s.dial(); err != nil {
+			return nil, err
+		}
+		reply, err = c.roundTrip(args)
+		if _, ok = err.(redisError); err != nil && !ok {
+			_ = c.conn.Close()
+			return nil, err
+		}
+	}
+	s.release(c)
+
+	return reply, err
+}
+
+func (s *redisStorage) acquire() (*redisConn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+		return s.dial()
+	}
+}
+
+// release keeps the connection for the next round trip,
+// the connections over the pool size are closed.
+func (s *redisStorage) release(c *redisConn) {
+	s.mx.Lock()
+	if !s.closed {
+		select {
+		case s.idle <- c:
+			s.mx.Unlock()
+			return
+		default:
+		}
+	}
+	s.mx.Unlock()
+	_ = c.conn.Close()
+}
+
+func (s *redisStorage) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", s.addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+	if s.password != "" {
+		if _, err = c.roundTrip([]string{"AUTH", s.password}); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err = c.roundTrip([]string{"SELECT", strconv.Itoa(s.db)}); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *redisConn) roundTrip(args []string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+
+	return readReply(c.rd)
+}
+
+func encodeValue(r *record) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeCommand(args []string) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	buf.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		buf.WriteString(arg)
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+// readReply reads a RESP reply: a string, an integer, a bulk string or an array,
+// nil bulk strings and arrays are returned as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
+
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
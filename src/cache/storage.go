@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// itemOverhead approximates the memory taken by an item besides its name
+// and value: the map entry, the lru element and the item itself.
+const itemOverhead = 128
+
+// Storage keeps the cache items. Cache serializes the changes of a local
+// storage, Get is called by concurrent readers. A SharedStorage is called
+// concurrently and locks itself.
+type Storage interface {
+	Get(name string) (*Item, bool)
+	Set(name string, item *Item)
+	// SetNX sets the item unless the name has an unexpired one
+	SetNX(name string, item *Item) bool
+	Delete(name string) bool
+	// Range calls f for every item until it returns false
+	Range(f func(name string, item *Item) bool)
+	Len() int
+	Close() error
+}
+
+// Limits bound the items kept in memory, zero means unlimited.
+type Limits struct {
+	MaxItems int
+	MaxBytes int64
+}
+
+// Stats of the items kept in memory, the sizes are approximate.
+type Stats struct {
+	Items        int    `json:"items"`
+	Bytes        int64  `json:"bytes"`
+	MaxItems     int    `json:"max_items"`
+	MaxBytes     int64  `json:"max_bytes"`
+	Evictions    uint64 `json:"evictions"`
+	EvictedBytes int64  `json:"evicted_bytes"`
+}
+
+// BoundedStorage evicts the least recently used items over the limits.
+type BoundedStorage interface {
+	SetLimits(limits Limits)
+	Stats() Stats
+}
+
+// SharedStorage keeps a part of the items on a server expiring them itself,
+// the garbage collector looks through the Local part only. Cache does not
+// hold its lock over the calls of a shared storage.
+type SharedStorage interface {
+	Local() Storage
+}
+
+type memoryItem struct {
+	name string
+	item *Item
+	size int64
+}
+
+// memoryStorage is the default process-local storage.
+type memoryStorage struct {
+	// the lru list is changed by Get of concurrent readers
+	mx      sync.Mutex
+	items   map[string]*list.Element
+	lru     *list.List // the most recently used items are in front
+	limits  Limits
+	stats   Stats
+	onEvict func(name string)
+}
+
+func NewMemoryStorage() Storage {
+	return newMemoryStorage()
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+func (s *memoryStorage) Get(name string) (*Item, bool) {
+	defer s.mx.Unlock()
+	s.mx.Lock()
+	el, ok := s.items[name]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+
+	return el.Value.(*memoryItem).item, true
+}
+
+// peek returns the item without touching the lru list.
+func (s *memoryStorage) peek(name string) (*Item, bool) {
+	defer s.mx.Unlock()
+	s.mx.Lock()
+	el, ok := s.items[name]
+	if !ok {
+		return nil, false
+	}
+	return el.Value.(*memoryItem).item, true
+}
+
+func (s *memoryStorage) Set(name string, item *Item) {
+	s.mx.Lock()
+	size := itemSize(name, item.value)
+	if el, ok := s.items[name]; ok {
+		mi := el.Value.(*memoryItem)
+		s.stats.Bytes += size - mi.size
+		mi.item, mi.size = item, size
+		s.lru.MoveToFront(el)
+	} else {
+		s.items[name] = s.lru.PushFront(&memoryItem{name: name, item: item, size: size})
+		s.stats.Bytes += size
+	}
+	evicted := s.evict()
+	s.mx.Unlock()
+
+	if s.onEvict != nil {
+		for _, name := range evicted {
+			s.onEvict(name)
+		}
+	}
+}
+
+func (s *memoryStorage) SetNX(name string, item *Item) bool {
+	if current, ok := s.peek(name); ok && !current.ttl.Before(time.Now()) {
+		return false
+	}
+	s.Set(name, item)
+	return true
+}
+
+func (s *memoryStorage) Delete(name string) bool {
+	defer s.mx.Unlock()
+	s.mx.Lock()
+	el, ok := s.items[name]
+	if !ok {
+		return false
+	}
+	s.remove(el)
+	return true
+}
+
+func (s *memoryStorage) Range(f func(name string, item *Item) bool) {
+	s.mx.Lock()
+	items := make([]*memoryItem, 0, len(s.items))
+	for _, el := range s.items {
+		items = append(items, el.Value.(*memoryItem))
+	}
+	s.mx.Unlock()
+	for _, mi := range items {
+		if !f(mi.name, mi.item) {
+			return
+		}
+	}
+}
+
+func (s *memoryStorage) Len() int {
+	defer s.mx.Unlock()
+	s.mx.Lock()
+	return len(s.items)
+}
+
+func (s *memoryStorage) Close() error {
+	return nil
+}
+
+func (s *memoryStorage) SetLimits(limits Limits) {
+	s.mx.Lock()
+	s.limits = limits
+	evicted := s.evict()
+	s.mx.Unlock()
+
+	if s.onEvict != nil {
+		for _, name := range evicted {
+			s.onEvict(name)
+		}
+	}
+}
+
+func (s *memoryStorage) Stats() Stats {
+	defer s.mx.Unlock()
+	s.mx.Lock()
+	stats := s.stats
+	stats.Items = len(s.items)
+	stats.MaxItems = s.limits.MaxItems
+	stats.MaxBytes = s.limits.MaxBytes
+
+	return stats
+}
+
+// evict removes the least recently used items over the limits, the most
+// recently used item and the pinned ones are always kept.
+func (s *memoryStorage) evict() (evicted []string) {
+	el := s.lru.Back()
+	for el != nil && el != s.lru.Front() && s.overLimits() {
+		mi := el.Value.(*memoryItem)
+		prev := el.Prev()
+		if mi.item.pinned {
+			el = prev
+			continue
+		}
+		s.remove(el)
+		s.stats.Evictions++
+		s.stats.EvictedBytes += mi.size
+		evicted = append(evicted, mi.name)
+		el = prev
+	}
+	return evicted
+}
+
+func (s *memoryStorage) overLimits() bool {
+	return (s.limits.MaxItems > 0 && len(s.items) > s.limits.MaxItems) ||
+		(s.limits.MaxBytes > 0 && s.stats.Bytes > s.limits.MaxBytes)
+}
+
+func (s *memoryStorage) remove(el *list.Element) {
+	mi := s.lru.Remove(el).(*memoryItem)
+	delete(s.items, mi.name)
+	s.stats.Bytes -= mi.size
+}
+
+// itemSize approximates the memory taken by the item.
+func itemSize(name string, value interface{}) int64 {
+	size := int64(itemOverhead + len(name))
+	switch v := value.(type) {
+	case *Entry:
+		size += int64(len(v.Kind) + len(v.Data))
+	case string:
+		size += int64(len(v))
+	case []byte:
+		size += int64(len(v))
+	case []string:
+		for _, s := range v {
+			size += int64(16 + len(s))
+		}
+	}
+	return size
+}
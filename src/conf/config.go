@@ -8,24 +8,66 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	OverloadMethodSimple = "simple"
-	OverloadMethodStrong = "strong"
+	OverloadModeBurst     = "burst"
+	OverloadModeSustained = "sustained"
+)
+
+const (
+	CacheStorageMemory = "memory"
+	CacheStorageFile   = "file"
+	CacheStorageRedis  = "redis"
 )
 
 type AppConfig struct {
-	ServerPort              int
-	CacheBgFrequency        time.Duration
-	CacheDebug              bool
-	CacheTtl                time.Duration
-	OverloadWorkers         int
-	OverloadInitConnections int
-	OverloadMaxLimit        int
-	OverloadMaxConnections  int
-	OverloadMethod          string
+	ServerPort               int
+	SitesRetryAfter          time.Duration
+	SitesRequestBudget       time.Duration
+	SitesStreamTimeout       time.Duration
+	BenchmarkAllowPrivate    bool
+	JobsHistory              int
+	JobsTimeout              time.Duration
+	SitemapSampleSize        int
+	SitemapMaxFiles          int
+	SitemapConcurrency       int
+	SitemapCacheTtl          time.Duration
+	YandexRegion             int
+	YandexPages              int
+	YandexNumDoc             int
+	YandexDevice             string
+	YandexBackoffMin         time.Duration
+	YandexBackoffMax         time.Duration
+	DomainSuffixList         string
+	CacheBgFrequency         time.Duration
+	CacheDebug               bool
+	CacheTtl                 time.Duration
+	CacheStaleTtl            time.Duration
+	CacheStorage             string
+	CacheFile                string
+	CacheRedisAddr           string
+	CacheRedisPassword       string
+	CacheRedisDb             int
+	CacheRedisPrefix         string
+	CacheMaxItems            int
+	CacheMaxMemory           int64 // bytes
+	OverloadWorkers          int
+	OverloadInitConnections  int
+	OverloadMaxLimit         int
+	OverloadMaxConnections   int
+	OverloadMethod           string
+	OverloadResponseTimeout  time.Duration
+	OverloadBisectTolerance  int
+	OverloadStepRounds       int
+	OverloadStepErrorRate    float64
+	OverloadMode             string
+	OverloadSustainDuration  time.Duration
+	OverloadRateLimitMarkers []string
+	OverloadRespectRobots    bool
+	OverloadUserAgent        string
 }
 
 type TestConfig struct {
@@ -80,14 +122,49 @@ func loadConfig() {
 			}
 		}
 		myEnv["APP_SERVER_PORT"] = getEnv("APP_SERVER_PORT")
+		myEnv["APP_SITES_RETRY_AFTER"] = getEnv("APP_SITES_RETRY_AFTER")
+		myEnv["APP_SITES_REQUEST_BUDGET"] = getEnv("APP_SITES_REQUEST_BUDGET")
+		myEnv["APP_SITES_STREAM_TIMEOUT"] = getEnv("APP_SITES_STREAM_TIMEOUT")
+		myEnv["APP_BENCHMARK_ALLOW_PRIVATE"] = getEnv("APP_BENCHMARK_ALLOW_PRIVATE")
+		myEnv["APP_JOBS_HISTORY"] = getEnv("APP_JOBS_HISTORY")
+		myEnv["APP_JOBS_TIMEOUT"] = getEnv("APP_JOBS_TIMEOUT")
+		myEnv["APP_SITEMAP_SAMPLE_SIZE"] = getEnv("APP_SITEMAP_SAMPLE_SIZE")
+		myEnv["APP_SITEMAP_MAX_FILES"] = getEnv("APP_SITEMAP_MAX_FILES")
+		myEnv["APP_SITEMAP_CONCURRENCY"] = getEnv("APP_SITEMAP_CONCURRENCY")
+		myEnv["APP_SITEMAP_CACHE_TTL"] = getEnv("APP_SITEMAP_CACHE_TTL")
+		myEnv["APP_YANDEX_REGION"] = getEnv("APP_YANDEX_REGION")
+		myEnv["APP_YANDEX_PAGES"] = getEnv("APP_YANDEX_PAGES")
+		myEnv["APP_YANDEX_NUMDOC"] = getEnv("APP_YANDEX_NUMDOC")
+		myEnv["APP_YANDEX_DEVICE"] = getEnv("APP_YANDEX_DEVICE")
+		myEnv["APP_YANDEX_BACKOFF_MIN"] = getEnv("APP_YANDEX_BACKOFF_MIN")
+		myEnv["APP_YANDEX_BACKOFF_MAX"] = getEnv("APP_YANDEX_BACKOFF_MAX")
+		myEnv["APP_DOMAIN_SUFFIX_LIST"] = getEnv("APP_DOMAIN_SUFFIX_LIST")
 		myEnv["APP_CACHE_BACKGROUND_FREQUENCY"] = getEnv("APP_CACHE_BACKGROUND_FREQUENCY")
 		myEnv["APP_CACHE_DEBUG"] = getEnv("APP_CACHE_DEBUG")
 		myEnv["APP_CACHE_TTL"] = getEnv("APP_CACHE_TTL")
+		myEnv["APP_CACHE_STALE_TTL"] = getEnv("APP_CACHE_STALE_TTL")
+		myEnv["APP_CACHE_STORAGE"] = getEnv("APP_CACHE_STORAGE")
+		myEnv["APP_CACHE_FILE"] = getEnv("APP_CACHE_FILE")
+		myEnv["APP_CACHE_REDIS_ADDR"] = getEnv("APP_CACHE_REDIS_ADDR")
+		myEnv["APP_CACHE_REDIS_PASSWORD"] = getEnv("APP_CACHE_REDIS_PASSWORD")
+		myEnv["APP_CACHE_REDIS_DB"] = getEnv("APP_CACHE_REDIS_DB")
+		myEnv["APP_CACHE_REDIS_PREFIX"] = getEnv("APP_CACHE_REDIS_PREFIX")
+		myEnv["APP_CACHE_MAX_ITEMS"] = getEnv("APP_CACHE_MAX_ITEMS")
+		myEnv["APP_CACHE_MAX_MEMORY"] = getEnv("APP_CACHE_MAX_MEMORY")
 		myEnv["APP_OVERLOAD_QUEUE_WORKERS"] = getEnv("APP_OVERLOAD_QUEUE_WORKERS")
 		myEnv["APP_OVERLOAD_QUEUE_WORKERS"] = getEnv("APP_OVERLOAD_QUEUE_WORKERS")
 		myEnv["APP_OVERLOAD_MAX_LIMIT"] = getEnv("APP_OVERLOAD_MAX_LIMIT")
 		myEnv["APP_OVERLOAD_MAX_CONNECTIONS"] = getEnv("APP_OVERLOAD_MAX_CONNECTIONS")
 		myEnv["APP_OVERLOAD_METHOD"] = getEnv("APP_OVERLOAD_METHOD")
+		myEnv["APP_OVERLOAD_RESPONSE_TIMEOUT"] = getEnv("APP_OVERLOAD_RESPONSE_TIMEOUT")
+		myEnv["APP_OVERLOAD_BISECT_TOLERANCE"] = getEnv("APP_OVERLOAD_BISECT_TOLERANCE")
+		myEnv["APP_OVERLOAD_STEP_ROUNDS"] = getEnv("APP_OVERLOAD_STEP_ROUNDS")
+		myEnv["APP_OVERLOAD_STEP_MAX_ERROR_RATE"] = getEnv("APP_OVERLOAD_STEP_MAX_ERROR_RATE")
+		myEnv["APP_OVERLOAD_MODE"] = getEnv("APP_OVERLOAD_MODE")
+		myEnv["APP_OVERLOAD_SUSTAIN_DURATION"] = getEnv("APP_OVERLOAD_SUSTAIN_DURATION")
+		myEnv["APP_OVERLOAD_RATE_LIMIT_MARKERS"] = getEnv("APP_OVERLOAD_RATE_LIMIT_MARKERS")
+		myEnv["APP_OVERLOAD_RESPECT_ROBOTS"] = getEnv("APP_OVERLOAD_RESPECT_ROBOTS")
+		myEnv["APP_OVERLOAD_USER_AGENT"] = getEnv("APP_OVERLOAD_USER_AGENT")
 	} else {
 		myEnv, err = godotenv.Read(fileName)
 		if err != nil {
@@ -142,6 +219,114 @@ func processEnv(env map[string]string) error {
 	}
 	config.ServerPort = port
 
+	retryAfter, err := strconv.Atoi(env["APP_SITES_RETRY_AFTER"])
+	if err != nil {
+		return err
+	}
+	config.SitesRetryAfter = time.Duration(retryAfter * 1_000_000_000)
+
+	budget, err := strconv.Atoi(env["APP_SITES_REQUEST_BUDGET"])
+	if err != nil {
+		return err
+	}
+	if budget <= 0 {
+		return errors.New("invalid sites request budget")
+	}
+	config.SitesRequestBudget = time.Duration(budget * 1_000_000_000)
+
+	streamTimeout, err := strconv.Atoi(env["APP_SITES_STREAM_TIMEOUT"])
+	if err != nil {
+		return err
+	}
+	if streamTimeout <= 0 {
+		return errors.New("invalid sites stream timeout")
+	}
+	config.SitesStreamTimeout = time.Duration(streamTimeout * 1_000_000_000)
+
+	config.BenchmarkAllowPrivate = "yes" == env["APP_BENCHMARK_ALLOW_PRIVATE"]
+
+	history, err := strconv.Atoi(env["APP_JOBS_HISTORY"])
+	if err != nil {
+		return err
+	}
+	config.JobsHistory = history
+
+	jobsTimeout, err := strconv.Atoi(env["APP_JOBS_TIMEOUT"])
+	if err != nil {
+		return err
+	}
+	if jobsTimeout <= 0 {
+		return errors.New("invalid jobs timeout")
+	}
+	config.JobsTimeout = time.Duration(jobsTimeout * 1_000_000_000)
+
+	sampleSize, err := strconv.Atoi(env["APP_SITEMAP_SAMPLE_SIZE"])
+	if err != nil {
+		return err
+	}
+	if sampleSize < 1 {
+		return errors.New("invalid sitemap sample size")
+	}
+	config.SitemapSampleSize = sampleSize
+
+	maxFiles, err := strconv.Atoi(env["APP_SITEMAP_MAX_FILES"])
+	if err != nil {
+		return err
+	}
+	if maxFiles < 1 {
+		return errors.New("invalid sitemap max files")
+	}
+	config.SitemapMaxFiles = maxFiles
+
+	sitemapConcurrency, err := strconv.Atoi(env["APP_SITEMAP_CONCURRENCY"])
+	if err != nil {
+		return err
+	}
+	if sitemapConcurrency < 1 {
+		return errors.New("invalid sitemap concurrency")
+	}
+	config.SitemapConcurrency = sitemapConcurrency
+
+	sitemapTtl, err := strconv.Atoi(env["APP_SITEMAP_CACHE_TTL"])
+	if err != nil {
+		return err
+	}
+	if sitemapTtl <= 0 {
+		return errors.New("invalid sitemap cache ttl")
+	}
+	config.SitemapCacheTtl = time.Duration(sitemapTtl * 1_000_000_000)
+
+	// validated by the yandex provider
+	config.YandexRegion, err = strconv.Atoi(env["APP_YANDEX_REGION"])
+	if err != nil {
+		return err
+	}
+	config.YandexPages, err = strconv.Atoi(env["APP_YANDEX_PAGES"])
+	if err != nil {
+		return err
+	}
+	config.YandexNumDoc, err = strconv.Atoi(env["APP_YANDEX_NUMDOC"])
+	if err != nil {
+		return err
+	}
+	config.YandexDevice = env["APP_YANDEX_DEVICE"]
+
+	backoffMin, err := strconv.Atoi(env["APP_YANDEX_BACKOFF_MIN"])
+	if err != nil {
+		return err
+	}
+	backoffMax, err := strconv.Atoi(env["APP_YANDEX_BACKOFF_MAX"])
+	if err != nil {
+		return err
+	}
+	if backoffMin <= 0 || backoffMax < backoffMin {
+		return errors.New("invalid yandex backoff")
+	}
+	config.YandexBackoffMin = time.Duration(backoffMin * 1_000_000_000)
+	config.YandexBackoffMax = time.Duration(backoffMax * 1_000_000_000)
+
+	config.DomainSuffixList = strings.TrimSpace(env["APP_DOMAIN_SUFFIX_LIST"])
+
 	freq, err := strconv.Atoi(env["APP_CACHE_BACKGROUND_FREQUENCY"])
 	if err != nil {
 		return err
@@ -156,6 +341,60 @@ func processEnv(env map[string]string) error {
 	}
 	config.CacheTtl = time.Duration(ttl * 1_000_000_000)
 
+	staleTtl, err := strconv.Atoi(env["APP_CACHE_STALE_TTL"])
+	if err != nil {
+		return err
+	}
+	if staleTtl < 0 {
+		return fmt.Errorf("invalid cache stale ttl: %d", staleTtl)
+	}
+	config.CacheStaleTtl = time.Duration(staleTtl * 1_000_000_000)
+
+	switch env["APP_CACHE_STORAGE"] {
+	case CacheStorageMemory:
+	case CacheStorageFile:
+		if strings.TrimSpace(env["APP_CACHE_FILE"]) == "" {
+			return errors.New("empty cache file")
+		}
+	case CacheStorageRedis:
+		if strings.TrimSpace(env["APP_CACHE_REDIS_ADDR"]) == "" {
+			return errors.New("empty cache redis address")
+		}
+	default:
+		return fmt.Errorf("invalid cache storage: %s", env["APP_CACHE_STORAGE"])
+	}
+	config.CacheStorage = env["APP_CACHE_STORAGE"]
+	config.CacheFile = strings.TrimSpace(env["APP_CACHE_FILE"])
+	config.CacheRedisAddr = strings.TrimSpace(env["APP_CACHE_REDIS_ADDR"])
+	config.CacheRedisPassword = env["APP_CACHE_REDIS_PASSWORD"]
+	redisDb, err := strconv.Atoi(env["APP_CACHE_REDIS_DB"])
+	if err != nil {
+		return err
+	}
+	if redisDb < 0 {
+		return fmt.Errorf("invalid cache redis db: %d", redisDb)
+	}
+	config.CacheRedisDb = redisDb
+	config.CacheRedisPrefix = env["APP_CACHE_REDIS_PREFIX"]
+
+	maxItems, err := strconv.Atoi(env["APP_CACHE_MAX_ITEMS"])
+	if err != nil {
+		return err
+	}
+	if maxItems < 0 {
+		return fmt.Errorf("invalid cache max items: %d", maxItems)
+	}
+	config.CacheMaxItems = maxItems
+
+	maxMemory, err := strconv.Atoi(env["APP_CACHE_MAX_MEMORY"])
+	if err != nil {
+		return err
+	}
+	if maxMemory < 0 {
+		return fmt.Errorf("invalid cache max memory: %d", maxMemory)
+	}
+	config.CacheMaxMemory = int64(maxMemory) << 20
+
 	workers, err := strconv.Atoi(env["APP_OVERLOAD_QUEUE_WORKERS"])
 	if err != nil {
 		return err
@@ -180,14 +419,72 @@ func processEnv(env map[string]string) error {
 	}
 	config.OverloadMaxConnections = maxCons
 
-	switch env["APP_OVERLOAD_METHOD"] {
-	case OverloadMethodSimple:
-		config.OverloadMethod = OverloadMethodSimple
-	case OverloadMethodStrong:
-		config.OverloadMethod = OverloadMethodStrong
-	default:
+	// the method is checked against registered strategies on the background start
+	if env["APP_OVERLOAD_METHOD"] == "" {
 		return errors.New("invalid overload method")
 	}
+	config.OverloadMethod = env["APP_OVERLOAD_METHOD"]
+
+	respTimeout, err := strconv.Atoi(env["APP_OVERLOAD_RESPONSE_TIMEOUT"])
+	if err != nil {
+		return err
+	}
+	if respTimeout <= 0 {
+		return fmt.Errorf("invalid overload response timeout: %d", respTimeout)
+	}
+	config.OverloadResponseTimeout = time.Duration(respTimeout * 1_000_000_000)
+
+	tolerance, err := strconv.Atoi(env["APP_OVERLOAD_BISECT_TOLERANCE"])
+	if err != nil {
+		return err
+	}
+	if tolerance < 1 {
+		return errors.New("invalid overload bisect tolerance")
+	}
+	config.OverloadBisectTolerance = tolerance
+
+	rounds, err := strconv.Atoi(env["APP_OVERLOAD_STEP_ROUNDS"])
+	if err != nil {
+		return err
+	}
+	if rounds < 1 {
+		return errors.New("invalid overload step rounds")
+	}
+	config.OverloadStepRounds = rounds
+
+	errorRate, err := strconv.ParseFloat(env["APP_OVERLOAD_STEP_MAX_ERROR_RATE"], 64)
+	if err != nil {
+		return err
+	}
+	if errorRate < 0 || errorRate > 100 {
+		return errors.New("invalid overload step max error rate")
+	}
+	config.OverloadStepErrorRate = errorRate
+
+	switch env["APP_OVERLOAD_MODE"] {
+	case OverloadModeBurst:
+		config.OverloadMode = OverloadModeBurst
+	case OverloadModeSustained:
+		config.OverloadMode = OverloadModeSustained
+	default:
+		return errors.New("invalid overload mode")
+	}
+
+	sustain, err := strconv.Atoi(env["APP_OVERLOAD_SUSTAIN_DURATION"])
+	if err != nil {
+		return err
+	}
+	config.OverloadSustainDuration = time.Duration(sustain * 1_000_000_000)
+
+	config.OverloadRateLimitMarkers = make([]string, 0)
+	for _, marker := range strings.Split(env["APP_OVERLOAD_RATE_LIMIT_MARKERS"], ",") {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			config.OverloadRateLimitMarkers = append(config.OverloadRateLimitMarkers, marker)
+		}
+	}
+
+	config.OverloadRespectRobots = "yes" == env["APP_OVERLOAD_RESPECT_ROBOTS"]
+	config.OverloadUserAgent = strings.TrimSpace(env["APP_OVERLOAD_USER_AGENT"])
 
 	return nil
 }
@@ -0,0 +1,5 @@
+package dataProvider
+
+import bing2 "lubyshev/go-site-benchmark/src/bing"
+
+var bingProvider = &searchEngine{namespace: DataProviderBing, search: bing2.GetBingSearchResult}
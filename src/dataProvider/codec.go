@@ -0,0 +1,114 @@
+package dataProvider
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/cache"
+	"time"
+)
+
+// schema versions are increased on every incompatible change of the encoding,
+// cached values of other versions are fetched again
+const (
+	hostsSchemaVersion   = 1
+	urlListSchemaVersion = 1
+)
+
+type hostsCodec struct{}
+
+func (hostsCodec) Kind() string {
+	return "hosts-to-check"
+}
+
+func (hostsCodec) Version() int {
+	return hostsSchemaVersion
+}
+
+func (hostsCodec) Encode(value interface{}) ([]byte, error) {
+	hosts, ok := value.(*HostsToCheck)
+	if !ok {
+		return nil, fmt.Errorf("hosts codec: unexpected value %T", value)
+	}
+	return encodeGob(hosts.Items)
+}
+
+func (hostsCodec) Decode(data []byte) (interface{}, error) {
+	items := make(map[string][]string)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&items); err != nil {
+		return nil, err
+	}
+	return &HostsToCheck{Items: items}, nil
+}
+
+type urlListCodec struct{}
+
+func (urlListCodec) Kind() string {
+	return "url-list"
+}
+
+func (urlListCodec) Version() int {
+	return urlListSchemaVersion
+}
+
+func (urlListCodec) Encode(value interface{}) ([]byte, error) {
+	urls, ok := value.([]string)
+	if !ok {
+		return nil, fmt.Errorf("url list codec: unexpected value %T", value)
+	}
+	return encodeGob(urls)
+}
+
+func (urlListCodec) Decode(data []byte) (interface{}, error) {
+	var urls []string
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func encodeGob(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func cacheHosts(name string, hosts *HostsToCheck, ttl time.Duration) {
+	if err := cache.GetCache().SetEntry(name, hostsCodec{}, hosts, ttl); err != nil {
+		log.Printf("ERROR: cache %s: %s\n", name, err.Error())
+	}
+}
+
+func getCachedHosts(name string) (*HostsToCheck, bool) {
+	v, ok := getEntry(name, hostsCodec{})
+	if !ok {
+		return nil, false
+	}
+	hosts, ok := v.(*HostsToCheck)
+	return hosts, ok
+}
+
+func cacheUrlList(name string, urls []string, ttl time.Duration) {
+	if err := cache.GetCache().SetEntry(name, urlListCodec{}, urls, ttl); err != nil {
+		log.Printf("ERROR: cache %s: %s\n", name, err.Error())
+	}
+}
+
+func getCachedUrlList(name string) ([]string, bool) {
+	v, ok := getEntry(name, urlListCodec{})
+	if !ok {
+		return nil, false
+	}
+	urls, ok := v.([]string)
+	return urls, ok
+}
+
+func getEntry(name string, codec cache.Codec) (interface{}, bool) {
+	v, err := cache.GetCache().GetEntry(name, codec)
+	if errors.Is(err, cache.ErrSchemaVersion) || errors.Is(err, cache.ErrUnexpectedKind) {
+		log.Printf("cached %s rejected: %s", name, err.Error())
+	}
+	return v, err == nil
+}
@@ -0,0 +1,5 @@
+package dataProvider
+
+import duckduckgo2 "lubyshev/go-site-benchmark/src/duckduckgo"
+
+var duckduckgoProvider = &searchEngine{namespace: DataProviderDuckDuckGo, search: duckduckgo2.GetDuckDuckGoSearchResult}
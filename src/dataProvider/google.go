@@ -0,0 +1,5 @@
+package dataProvider
+
+import google2 "lubyshev/go-site-benchmark/src/google"
+
+var googleProvider = &searchEngine{namespace: DataProviderGoogle, search: google2.GetGoogleSearchResult}
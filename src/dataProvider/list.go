@@ -0,0 +1,140 @@
+package dataProvider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"lubyshev/go-site-benchmark/src/domain"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrEmptyList = errors.New("empty url list")
+
+var privateHosts struct {
+	mx      sync.RWMutex
+	allowed bool
+}
+
+// AllowPrivateHosts lets the urls point to loopback, private and link-local
+// addresses, the load is never sent to them by default.
+func AllowPrivateHosts(allowed bool) {
+	defer privateHosts.mx.Unlock()
+	privateHosts.mx.Lock()
+	privateHosts.allowed = allowed
+}
+
+var listProvider list
+
+// list takes the urls from the query itself: a JSON array of strings
+// or one url per line. Urls without a scheme are treated as https.
+type list struct{}
+
+func (l list) GetData(_ context.Context, query string) (*HostsToCheck, error) {
+	urls, err := parseUrlList(query)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUrls(urls)
+}
+
+// ParseUrls groups the urls by root domain, urls without a scheme
+// are treated as https.
+func ParseUrls(urls []string) (*HostsToCheck, error) {
+	result := make(map[string][]string)
+	for _, rawUrl := range urls {
+		rawUrl = strings.TrimSpace(rawUrl)
+		if rawUrl == "" {
+			continue
+		}
+		u, err := normalizeUrl(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		host := domain.GetRootDomain(u.Hostname())
+		result[host] = mergeUrls(result[host], []string{u.String()})
+	}
+	if len(result) == 0 {
+		return nil, ErrEmptyList
+	}
+
+	return &HostsToCheck{Items: result}, nil
+}
+
+func parseUrlList(query string) ([]string, error) {
+	query = strings.TrimSpace(query)
+	var urls []string
+	if strings.HasPrefix(query, "[") {
+		if err := json.Unmarshal([]byte(query), &urls); err != nil {
+			return nil, fmt.Errorf("invalid url list: %v", err)
+		}
+	} else {
+		urls = strings.Split(query, "\n")
+	}
+	res := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			res = append(res, u)
+		}
+	}
+
+	return res, nil
+}
+
+func normalizeUrl(rawUrl string) (*url.URL, error) {
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %v", rawUrl, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || !validHost(u.Hostname()) {
+		return nil, fmt.Errorf("invalid url %q", rawUrl)
+	}
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u, nil
+}
+
+// validHost accepts public IP addresses and domain names of two labels at least,
+// so "https://https://" is not taken for the host "https".
+func validHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		defer privateHosts.mx.RUnlock()
+		privateHosts.mx.RLock()
+		return privateHosts.allowed || !privateIP(ip)
+	}
+	if _, err := idna.Lookup.ToASCII(host); err != nil {
+		return false
+	}
+	return strings.Contains(strings.Trim(host, "."), ".")
+}
+
+var privateNets = []*net.IPNet{
+	{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(172, 16, 0, 0), Mask: net.CIDRMask(12, 32)},
+	{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)},
+	{IP: net.IP{0xfc, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, Mask: net.CIDRMask(7, 128)},
+}
+
+// privateIP reports whether the address is loopback, private (RFC 1918, RFC 4193),
+// link-local (the cloud metadata 169.254.169.254 included) or unspecified.
+func privateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package dataProvider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// merge unions the hosts found by the engines. It fails only
+// when all of the engines failed.
+type merge struct {
+	engines  []string
+	adapters []OverloadSitesToCheck
+}
+
+func (m *merge) GetData(ctx context.Context, query string) (*HostsToCheck, error) {
+	results := make([]*HostsToCheck, len(m.engines))
+	errs := make([]error, len(m.engines))
+	wg := sync.WaitGroup{}
+	for i, adapter := range m.adapters {
+		wg.Add(1)
+		go func(i int, adapter OverloadSitesToCheck) {
+			defer wg.Done()
+			results[i], errs[i] = adapter.GetData(ctx, query)
+		}(i, adapter)
+	}
+	wg.Wait()
+
+	result := make(map[string][]string)
+	partial := false
+	failed := 0
+	for i, res := range results {
+		if errs[i] != nil {
+			log.Printf("ERROR: %s search failed: %s\n", m.engines[i], errs[i].Error())
+			failed++
+			continue
+		}
+		for host, urls := range res.Items {
+			result[host] = mergeUrls(result[host], urls)
+		}
+		partial = partial || res.Partial
+	}
+	if failed == len(m.engines) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &MergeError{Engines: m.engines, Errors: errs}
+	}
+
+	return &HostsToCheck{Items: result, Partial: partial}, nil
+}
+
+// MergeError is returned when all of the engines failed, errors.Is
+// and errors.As match the error of any engine, e.g. serp.ErrSearchBlocked.
+type MergeError struct {
+	Engines []string
+	Errors  []error
+}
+
+func (e *MergeError) Error() string {
+	failed := make([]string, 0, len(e.Errors))
+	for i, err := range e.Errors {
+		failed = append(failed, fmt.Sprintf("%s: %s", e.Engines[i], err.Error()))
+	}
+	return "all engines failed: " + strings.Join(failed, "; ")
+}
+
+func (e *MergeError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MergeError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,21 +1,102 @@
 package dataProvider
 
-const DataProviderYandex = "yandex"
+import (
+	"context"
+	"fmt"
+	yandex2 "lubyshev/go-site-benchmark/src/yandex"
+	"strings"
+)
+
+const (
+	DataProviderYandex     = "yandex"
+	DataProviderGoogle     = "google"
+	DataProviderBing       = "bing"
+	DataProviderDuckDuckGo = "duckduckgo"
+	DataProviderList       = "list"
+	DataProviderSitemap    = "sitemap"
+	// unions the hosts of all search engines
+	DataProviderMerge = "merge"
+)
 
 type OverloadSitesToCheck interface {
-	GetData(query string) (*HostsToCheck, error)
+	GetData(ctx context.Context, query string) (*HostsToCheck, error)
 }
 
 type HostsToCheck struct {
 	// [host]isSecure
 	Items map[string][]string
+	// the search was interrupted, Items hold the hosts found before
+	Partial bool
 }
 
 func GetAdapter(name string) OverloadSitesToCheck {
 	switch name {
 	case DataProviderYandex:
-		return yandexProvider
+		return getYandexProvider()
+	case DataProviderGoogle:
+		return googleProvider
+	case DataProviderBing:
+		return bingProvider
+	case DataProviderDuckDuckGo:
+		return duckduckgoProvider
+	case DataProviderList:
+		return listProvider
+	case DataProviderSitemap:
+		return sitemapProvider
 	}
 
 	return nil
 }
+
+// SearchEngines returns the providers taking a search phrase as the query.
+func SearchEngines() []string {
+	return []string{DataProviderYandex, DataProviderGoogle, DataProviderBing, DataProviderDuckDuckGo}
+}
+
+// GetSearchAdapter returns the adapter of the engine, the merge adapter
+// for "merge" or a comma separated list of engines, yandex by default.
+// Yandex is searched with the params.
+func GetSearchAdapter(engine string, params yandex2.SearchParams) (OverloadSitesToCheck, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	engine = strings.ToLower(strings.TrimSpace(engine))
+	var engines []string
+	switch {
+	case engine == "":
+		engines = []string{DataProviderYandex}
+	case engine == DataProviderMerge:
+		engines = SearchEngines()
+	default:
+		for _, name := range strings.Split(engine, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				engines = append(engines, name)
+			}
+		}
+	}
+	adapters := make([]OverloadSitesToCheck, 0, len(engines))
+	for _, name := range engines {
+		if !IsSearchEngine(name) {
+			return nil, fmt.Errorf("unknown search engine: %s", name)
+		}
+		if name == DataProviderYandex && params != YandexParams() {
+			adapters = append(adapters, NewYandexAdapter(params))
+			continue
+		}
+		adapters = append(adapters, GetAdapter(name))
+	}
+	if len(adapters) == 1 {
+		return adapters[0], nil
+	}
+
+	return &merge{engines: engines, adapters: adapters}, nil
+}
+
+func IsSearchEngine(name string) bool {
+	for _, engine := range SearchEngines() {
+		if engine == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package dataProvider
+
+import (
+	"context"
+	"lubyshev/go-site-benchmark/src/serp"
+	"time"
+)
+
+const searchCacheTtl = 600 * time.Second
+
+// searchEngine groups the search results by host,
+// results are cached in the namespace of the engine, partial ones are not.
+type searchEngine struct {
+	namespace string
+	search    func(ctx context.Context, query string) (*serp.ResponseStruct, error)
+}
+
+func (s *searchEngine) GetData(ctx context.Context, query string) (*HostsToCheck, error) {
+	if res, ok := getCachedHosts(s.namespace + "::" + query); ok {
+		return res, nil
+	}
+	data, err := s.search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	for _, item := range data.Items {
+		result[item.Host] = mergeUrls(result[item.Host], []string{item.Url})
+	}
+	res := &HostsToCheck{
+		Items:   result,
+		Partial: data.Partial,
+	}
+	if !res.Partial {
+		cacheHosts(s.namespace+"::"+query, res, searchCacheTtl)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,180 @@
+package dataProvider
+
+import (
+	"context"
+	"lubyshev/go-site-benchmark/src/domain"
+	"lubyshev/go-site-benchmark/src/robots"
+	sitemap2 "lubyshev/go-site-benchmark/src/sitemap"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSitemapSampleSize  = 10
+	defaultSitemapMaxFiles    = 10
+	defaultSitemapConcurrency = 4
+	defaultSitemapCacheTtl    = 600 * time.Second
+)
+
+var sitemapProvider = &sitemap{
+	sampleSize:  defaultSitemapSampleSize,
+	maxFiles:    defaultSitemapMaxFiles,
+	concurrency: defaultSitemapConcurrency,
+	ttl:         defaultSitemapCacheTtl,
+}
+
+// sitemap takes the hosts from the query (same format as the list provider)
+// and samples page urls of every host from its sitemaps.
+type sitemap struct {
+	sampleSize  int
+	maxFiles    int
+	concurrency int
+	ttl         time.Duration
+	mx          sync.RWMutex
+}
+
+// ConfigureSitemap sets the number of sampled urls per host, the max number
+// of sitemap files fetched per host, the number of sites discovered
+// at the same time and the cache ttl of the sampled urls.
+func ConfigureSitemap(sampleSize int, maxFiles int, concurrency int, ttl time.Duration) {
+	defer sitemapProvider.mx.Unlock()
+	sitemapProvider.mx.Lock()
+	sitemapProvider.sampleSize = sampleSize
+	sitemapProvider.maxFiles = maxFiles
+	sitemapProvider.concurrency = concurrency
+	sitemapProvider.ttl = ttl
+}
+
+type sitemapDiscovery struct {
+	host   string
+	site   string
+	sample []string
+	err    error
+}
+
+func (s *sitemap) GetData(ctx context.Context, query string) (*HostsToCheck, error) {
+	sites, err := listProvider.GetData(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.mx.RLock()
+	sampleSize, maxFiles, concurrency, ttl := s.sampleSize, s.maxFiles, s.concurrency, s.ttl
+	s.mx.RUnlock()
+
+	discoveries := make([]*sitemapDiscovery, 0, len(sites.Items))
+	for host, urls := range sites.Items {
+		for _, rawUrl := range urls {
+			u, _ := url.Parse(rawUrl)
+			discoveries = append(discoveries, &sitemapDiscovery{host: host, site: u.Scheme + "://" + u.Host})
+		}
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, d := range discoveries {
+		wg.Add(1)
+		go func(d *sitemapDiscovery) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				d.err = ctx.Err()
+				return
+			}
+			defer func() {
+				<-sem
+			}()
+			d.sample, d.err = s.discover(ctx, d.site, d.host, sampleSize, maxFiles, ttl)
+		}(d)
+	}
+	wg.Wait()
+
+	result := make(map[string][]string)
+	for _, d := range discoveries {
+		if d.err != nil {
+			return nil, d.err
+		}
+		result[d.host] = mergeUrls(result[d.host], d.sample)
+	}
+
+	return &HostsToCheck{Items: result}, nil
+}
+
+// discover samples urls of the root domain from the sitemaps of the site.
+// The site itself is returned if no sitemaps are found.
+func (s *sitemap) discover(
+	ctx context.Context,
+	site string,
+	host string,
+	sampleSize int,
+	maxFiles int,
+	ttl time.Duration,
+) ([]string, error) {
+	cacheKey := "sitemap::" + site
+	if res, ok := getCachedUrlList(cacheKey); ok {
+		return res, nil
+	}
+
+	rules, err := robots.Fetch(ctx, site, "")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		rules = &robots.Robots{}
+	}
+	sitemaps := rules.Sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{site + "/sitemap.xml"}
+	}
+	found, err := sitemap2.Collect(ctx, sitemaps, maxFiles)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	urls := make([]string, 0, len(found))
+	for _, rawUrl := range found {
+		u, err := url.Parse(rawUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if domain.GetRootDomain(u.Hostname()) == host {
+			urls = append(urls, u.String())
+		}
+	}
+	res := sampleUrls(mergeUrls(nil, urls), sampleSize)
+	if len(res) == 0 {
+		res = []string{site + "/"}
+	}
+	cacheUrlList(cacheKey, res, ttl)
+
+	return res, nil
+}
+
+// sampleUrls takes evenly spaced urls of the sorted list.
+func sampleUrls(urls []string, size int) []string {
+	if len(urls) <= size {
+		return urls
+	}
+	res := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		res = append(res, urls[i*len(urls)/size])
+	}
+
+	return res
+}
+
+// mergeUrls adds urls to the sorted list without duplicates.
+func mergeUrls(dst []string, urls []string) []string {
+	for _, u := range urls {
+		i := sort.SearchStrings(dst, u)
+		if i < len(dst) && dst[i] == u {
+			continue
+		}
+		dst = append(dst, "")
+		copy(dst[i+1:], dst[i:])
+		dst[i] = u
+	}
+
+	return dst
+}
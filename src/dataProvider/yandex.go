@@ -1,42 +1,58 @@
 package dataProvider
 
 import (
-	"lubyshev/go-site-benchmark/src/cache"
+	"context"
+	"lubyshev/go-site-benchmark/src/serp"
 	yandex2 "lubyshev/go-site-benchmark/src/yandex"
-	"sort"
-	"time"
+	"sync"
 )
 
-var yandexProvider yandex
+var defaultYandexParams = yandex2.SearchParams{
+	Region: 213,
+	Pages:  1,
+	NumDoc: 50,
+	Device: yandex2.DeviceTouch,
+}
 
-type yandex struct{}
+var (
+	yandexProvider = NewYandexAdapter(defaultYandexParams)
+	yandexParams   = defaultYandexParams
+	yandexMx       sync.RWMutex
+)
 
-func (y yandex) GetData(query string) (*HostsToCheck, error) {
-	iRes, err := cache.GetCache().Get("yandex::" + query)
-	if err == nil {
-		res := iRes.(*HostsToCheck)
-		return res, nil
-	}
-	data, err := yandex2.GetYandexSearchResult(query)
-	if err != nil {
-		return nil, err
+// ConfigureYandex sets the search params of the yandex provider.
+func ConfigureYandex(params yandex2.SearchParams) error {
+	if err := params.Validate(); err != nil {
+		return err
 	}
-	result := make(map[string][]string)
-	for _, item := range data.Items {
-		if _, ok := result[item.Host]; !ok {
-			result[item.Host] = make([]string, 0)
-		}
-		l := len(result[item.Host])
-		sort.Strings(result[item.Host])
-		i := sort.Search(l, func(i int) bool { return result[item.Host][i] >= item.Url })
-		if i >= len(result[item.Host]) || result[item.Host][i] != item.Url {
-			result[item.Host] = append(result[item.Host], item.Url)
-		}
-	}
-	res := &HostsToCheck{
-		Items: result,
-	}
-	cache.GetCache().Set("yandex::"+query, res, 600*time.Second)
+	defer yandexMx.Unlock()
+	yandexMx.Lock()
+	yandexParams = params
+	yandexProvider = NewYandexAdapter(params)
+
+	return nil
+}
+
+// YandexParams returns the configured search params.
+func YandexParams() yandex2.SearchParams {
+	defer yandexMx.RUnlock()
+	yandexMx.RLock()
+	return yandexParams
+}
 
-	return res, nil
+func getYandexProvider() OverloadSitesToCheck {
+	defer yandexMx.RUnlock()
+	yandexMx.RLock()
+	return yandexProvider
+}
+
+// NewYandexAdapter returns the yandex provider with the search params,
+// results are cached per params.
+func NewYandexAdapter(params yandex2.SearchParams) OverloadSitesToCheck {
+	return &searchEngine{
+		namespace: DataProviderYandex + "?" + params.String(),
+		search: func(ctx context.Context, query string) (*serp.ResponseStruct, error) {
+			return yandex2.GetYandexSearchResult(ctx, query, params)
+		},
+	}
 }
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// GetRootDomain returns the registrable domain (eTLD+1) of the host by the
+// Public Suffix List, e.g. "news.example.co.uk" -> "example.co.uk".
+// Internationalized domains are returned in the punycode form, IP addresses
+// and public suffixes themselves are kept as is.
+func GetRootDomain(domain string) string {
+	domain = Normalize(domain)
+	if net.ParseIP(domain) != nil {
+		return domain
+	}
+
+	return getList().rootDomain(domain)
+}
+
+// Normalize lowercases the host and converts it to the punycode form,
+// so "пример.рф" and "xn--e1afmkfd.xn--p1ai" are the same host.
+func Normalize(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		return ascii
+	}
+	return domain
+}
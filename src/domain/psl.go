@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+// snapshot of https://publicsuffix.org/list/public_suffix_list.dat
+//
+//go:embed public_suffix_list.dat
+var embeddedList string
+
+// rule flags, "ck" is both a wildcard ("*.ck") and a normal rule if listed so
+const (
+	ruleNormal = 1 << iota
+	ruleWildcard
+	ruleException
+)
+
+// List is a parsed Public Suffix List, rules are kept in the punycode form.
+type List struct {
+	rules map[string]int
+}
+
+var (
+	list     *List
+	listOnce sync.Once
+	listMx   sync.RWMutex
+)
+
+func getList() *List {
+	listOnce.Do(func() {
+		l, err := ParseList(strings.NewReader(embeddedList))
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded public suffix list: %s", err.Error()))
+		}
+		listMx.Lock()
+		if list == nil {
+			list = l
+		}
+		listMx.Unlock()
+	})
+	defer listMx.RUnlock()
+	listMx.RLock()
+	return list
+}
+
+// LoadList replaces the embedded list by the list file,
+// e.g. a fresh copy of public_suffix_list.dat.
+func LoadList(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	l, err := ParseList(f)
+	if err != nil {
+		return fmt.Errorf("invalid public suffix list %s: %v", fileName, err)
+	}
+	// the embedded list must not override the loaded one
+	listOnce.Do(func() {})
+	defer listMx.Unlock()
+	listMx.Lock()
+	list = l
+
+	return nil
+}
+
+// ParseList reads the list in the publicsuffix.org format, both
+// the ICANN and the private sections are used.
+func ParseList(r io.Reader) (*List, error) {
+	l := &List{rules: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// rules end at the first whitespace
+		rule := strings.ToLower(strings.Fields(line)[0])
+		kind := ruleNormal
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			kind, rule = ruleException, rule[1:]
+		case strings.HasPrefix(rule, "*."):
+			kind, rule = ruleWildcard, rule[2:]
+		}
+		ascii, err := idna.Lookup.ToASCII(rule)
+		if err != nil {
+			ascii = rule
+		}
+		l.rules[ascii] |= kind
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.rules) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+
+	return l, nil
+}
+
+// suffixLabels returns the number of labels of the public suffix: an exception
+// rule prevails, otherwise the longest matching rule does, "*" if none match.
+func (l *List) suffixLabels(labels []string) int {
+	for i := range labels {
+		if l.rules[strings.Join(labels[i:], ".")]&ruleException != 0 {
+			return len(labels) - i - 1
+		}
+	}
+	for i := range labels {
+		kind := l.rules[strings.Join(labels[i:], ".")]
+		if kind&ruleWildcard != 0 && i > 0 {
+			return len(labels) - i + 1
+		}
+		if kind&ruleNormal != 0 {
+			return len(labels) - i
+		}
+	}
+	return 1
+}
+
+func (l *List) rootDomain(domain string) string {
+	labels := strings.Split(domain, ".")
+	n := l.suffixLabels(labels)
+	if n >= len(labels) {
+		return domain
+	}
+
+	return strings.Join(labels[len(labels)-n-1:], ".")
+}
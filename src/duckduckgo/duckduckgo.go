@@ -0,0 +1,65 @@
+package duckduckgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"lubyshev/go-site-benchmark/src/domain"
+	"lubyshev/go-site-benchmark/src/serp"
+	"net/url"
+	"strings"
+)
+
+const searchDuckDuckGoTemplate = "https://html.duckduckgo.com/html/?q=%s"
+
+// engine recognizes the anomaly (bot challenge) page of the html version
+var engine = &serp.Engine{
+	Name:    "duckduckgo",
+	Markers: [][]byte{[]byte("anomaly-modal"), []byte("/anomaly.js")},
+}
+
+func GetDuckDuckGoSearchResult(ctx context.Context, searchPhrase string) (res *serp.ResponseStruct, err error) {
+	body, err := engine.Fetch(ctx, fmt.Sprintf(searchDuckDuckGoTemplate, url.QueryEscape(searchPhrase)))
+	if err != nil {
+		return nil, err
+	}
+	return ParseDuckDuckGoResponse(body)
+}
+
+// ParseDuckDuckGoResponse takes organic results of the html version,
+// "/l/?uddg=" redirects are resolved to the target urls.
+func ParseDuckDuckGoResponse(response []byte) (sites *serp.ResponseStruct, err error) {
+	res := serp.ResponseStruct{Items: make([]serp.ResponseItem, 0)}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response))
+	if err != nil {
+		err = fmt.Errorf("can't create parser for body: %v", err)
+		return
+	}
+	doc.Find("div.result").Each(func(i int, selection *goquery.Selection) {
+		if selection.Is(".result--ad") {
+			return
+		}
+		urlStr, ok := selection.Find("a.result__a").First().Attr("href")
+		if !ok {
+			return
+		}
+		if strings.HasPrefix(urlStr, "//") {
+			urlStr = "https:" + urlStr
+		}
+		if u, err := url.Parse(urlStr); err == nil && serp.IsDomainOf(u.Hostname(), "duckduckgo.com") && u.Path == "/l/" {
+			urlStr = u.Query().Get("uddg")
+		}
+
+		u, err := url.Parse(urlStr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+
+		res.Items = append(res.Items, serp.ResponseItem{
+			Host: domain.GetRootDomain(u.Hostname()),
+			Url:  urlStr,
+		})
+	})
+	return &res, nil
+}
@@ -0,0 +1,68 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"lubyshev/go-site-benchmark/src/domain"
+	"lubyshev/go-site-benchmark/src/serp"
+	"net/url"
+	"strings"
+)
+
+const searchGoogleTemplate = "https://www.google.com/search?num=50&hl=ru&q=%s"
+
+// engine recognizes the "/sorry/" page google shows to robots
+var engine = &serp.Engine{
+	Name:         "google",
+	CaptchaPaths: []string{"/sorry/"},
+	Markers:      [][]byte{[]byte("g-recaptcha"), []byte("/sorry/index")},
+}
+
+func GetGoogleSearchResult(ctx context.Context, searchPhrase string) (res *serp.ResponseStruct, err error) {
+	body, err := engine.Fetch(ctx, fmt.Sprintf(searchGoogleTemplate, url.QueryEscape(searchPhrase)))
+	if err != nil {
+		return nil, err
+	}
+	return ParseGoogleResponse(body)
+}
+
+// ParseGoogleResponse takes organic results: links with a title, both the plain
+// ones and the "/url?q=" redirects of the basic html version.
+func ParseGoogleResponse(response []byte) (sites *serp.ResponseStruct, err error) {
+	res := serp.ResponseStruct{Items: make([]serp.ResponseItem, 0)}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response))
+	if err != nil {
+		err = fmt.Errorf("can't create parser for body: %v", err)
+		return
+	}
+	doc.Find("a:has(h3)").Each(func(i int, link *goquery.Selection) {
+		if link.ParentsFiltered("#tads, #bottomads, [data-text-ad]").Length() > 0 {
+			return
+		}
+		urlStr, _ := link.Attr("href")
+		if strings.HasPrefix(urlStr, "/url?") {
+			q, err := url.ParseQuery(strings.TrimPrefix(urlStr, "/url?"))
+			if err != nil {
+				return
+			}
+			urlStr = q.Get("q")
+		}
+
+		u, err := url.Parse(urlStr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		host := domain.GetRootDomain(u.Hostname())
+		if u.Host == "" || strings.HasPrefix(host, "google.") {
+			return
+		}
+
+		res.Items = append(res.Items, serp.ResponseItem{
+			Host: host,
+			Url:  urlStr,
+		})
+	})
+	return &res, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/conf"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"net/http"
+)
+
+const maxUrlListSize = 1 << 20
+
+// Benchmark serves POST /benchmark: the body is a JSON array of urls
+// or one url per line. With ?source=sitemap the urls of every host
+// are sampled from its sitemaps instead.
+func Benchmark(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered in handlers.Benchmark()", r)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, "Internal error: %v", r)
+		}
+	}()
+	log.Printf("START BENCHMARK FROM: %s\n", req.RemoteAddr)
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = fmt.Fprintf(w, "Method not allowed")
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxUrlListSize))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, _ = fmt.Fprintf(w, "Url list is too large")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), conf.GetConfig().SitesRequestBudget)
+	defer cancel()
+
+	source := dataProvider.DataProviderList
+	if req.URL.Query().Get("source") == dataProvider.DataProviderSitemap {
+		source = dataProvider.DataProviderSitemap
+	}
+	sites, err := dataProvider.GetAdapter(source).GetData(ctx, string(body))
+	if ctx.Err() == context.DeadlineExceeded {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		_, _ = fmt.Fprintf(w, "Url discovery timed out")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Invalid url list: %s", err.Error())
+		return
+	}
+
+	test := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+
+	result, err := test.Benchmark(ctx, sites, conf.GetConfig().CacheTtl)
+	if err != nil || result == nil {
+		if err == nil {
+			err = errors.New("unexpected error")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "bencmark failed: %s", err.Error())
+		return
+	}
+
+	writeResult(w, req, result)
+	log.Printf("FINISH BENCHMARK FROM: %s\n===\n", req.RemoteAddr)
+}
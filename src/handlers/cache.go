@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/cache"
+	"net/http"
+)
+
+// CacheStats serves /cache/stats: the memory usage and evictions of the cache.
+func CacheStats(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered in handlers.CacheStats()", r)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, "Internal error: %v", r)
+		}
+	}()
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cache.GetCache().Stats())
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/jobs"
+	"lubyshev/go-site-benchmark/src/yandex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type jobRequest struct {
+	Search string   `json:"search"`
+	Engine string   `json:"engine"`
+	Hosts  []string `json:"hosts"`
+	// yandex params, the configured ones if zero
+	Region int    `json:"region"`
+	Pages  int    `json:"pages"`
+	NumDoc int    `json:"numdoc"`
+	Device string `json:"device"`
+}
+
+type jobResponse struct {
+	Id     string `json:"id"`
+	State  string `json:"state"`
+	Search string `json:"search,omitempty"`
+	Engine string `json:"engine,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// the search was blocked on a later page
+	PartialSearch bool                     `json:"partial_search,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+	FinishedAt    *time.Time               `json:"finished_at,omitempty"`
+	Hosts         []string                 `json:"hosts"`
+	Progress      jobProgress              `json:"progress"`
+	Results       map[string]*hostResponse `json:"results,omitempty"`
+}
+
+type jobProgress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+}
+
+// Jobs serves /jobs and /jobs/{id}.
+func Jobs(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered in handlers.Jobs()", r)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, "Internal error: %v", r)
+		}
+	}()
+
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/jobs"), "/")
+	switch {
+	case id == "" && req.Method == http.MethodPost:
+		createJob(w, req)
+	case id == "" && req.Method == http.MethodGet:
+		listJobs(w)
+	case id != "" && req.Method == http.MethodGet:
+		getJob(w, id)
+	case id != "" && req.Method == http.MethodDelete:
+		cancelJob(w, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = fmt.Fprintf(w, "Method not allowed")
+	}
+}
+
+func createJob(w http.ResponseWriter, req *http.Request) {
+	body := jobRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Invalid job request: %s", err.Error())
+		return
+	}
+	job, err := jobs.GetManager().Create(body.Search, body.Engine, body.yandexParams(), body.Hosts)
+	if err == jobs.ErrEmptyJob || err == jobs.ErrInvalidSearch || errors.Is(err, jobs.ErrInvalidHosts) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Invalid job request: %s", err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "Job creation failed: %s", err.Error())
+		return
+	}
+	log.Printf("job %s created for %s\n", job.Id, req.RemoteAddr)
+	writeJson(w, http.StatusAccepted, newJobResponse(job, false))
+}
+
+func (r *jobRequest) yandexParams() yandex.SearchParams {
+	params := dataProvider.YandexParams()
+	if r.Region != 0 {
+		params.Region = r.Region
+	}
+	if r.Pages != 0 {
+		params.Pages = r.Pages
+	}
+	if r.NumDoc != 0 {
+		params.NumDoc = r.NumDoc
+	}
+	if r.Device != "" {
+		params.Device = r.Device
+	}
+	return params
+}
+
+func listJobs(w http.ResponseWriter) {
+	list := jobs.GetManager().List()
+	res := make([]*jobResponse, 0, len(list))
+	for _, job := range list {
+		res = append(res, newJobResponse(job, false))
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+func getJob(w http.ResponseWriter, id string) {
+	job, err := jobs.GetManager().Get(id)
+	if err == jobs.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "Job %s not found", id)
+		return
+	}
+	writeJson(w, http.StatusOK, newJobResponse(job, true))
+}
+
+func cancelJob(w http.ResponseWriter, id string) {
+	err := jobs.GetManager().Cancel(id)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case jobs.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "Job %s not found", id)
+	default:
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprintf(w, "Job %s: %s", id, err.Error())
+	}
+}
+
+func newJobResponse(job *jobs.Job, withResults bool) *jobResponse {
+	res := &jobResponse{
+		Id:        job.Id,
+		State:     job.State,
+		Search:    job.Search,
+		Engine:    job.Engine,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		Hosts:     make([]string, 0),
+	}
+	if !job.FinishedAt.IsZero() {
+		res.FinishedAt = &job.FinishedAt
+	}
+	if job.Sites != nil {
+		for host := range job.Sites.Items {
+			res.Hosts = append(res.Hosts, host)
+		}
+		sort.Strings(res.Hosts)
+		res.Progress.Total = len(job.Sites.Items)
+		res.PartialSearch = job.Sites.Partial
+	}
+	for _, host := range job.Results {
+		if host.Done() {
+			res.Progress.Done++
+		}
+	}
+	if withResults {
+		res.Results = make(map[string]*hostResponse, len(job.Results))
+		for hostName, host := range job.Results {
+			res.Results[hostName] = newHostResponse(host)
+		}
+	}
+
+	return res
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
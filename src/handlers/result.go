@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/conf"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	statusReady      = "ready"
+	statusInProgress = "in-progress"
+	statusFailed     = "failed"
+	statusNotTested  = "not-tested"
+	statusQueued     = "queued"
+)
+
+type hostResponse struct {
+	Recommended   int                  `json:"recommended"`
+	Status        string               `json:"status"`
+	Partial       bool                 `json:"partial"`
+	Urls          []string             `json:"urls"`
+	Confidence    float64              `json:"confidence"`
+	Throughput    float64              `json:"throughput"`
+	Errors        map[string]int       `json:"errors"`
+	Latency       latencyResponse      `json:"latency"`
+	Timeline      [][]intervalResponse `json:"timeline"`
+	RateLimitedAt int                  `json:"rate_limited_at,omitempty"`
+	RetryAfter    int                  `json:"retry_after,omitempty"`
+	Disallowed    []string             `json:"disallowed,omitempty"`
+	CrawlDelay    float64              `json:"crawl_delay,omitempty"` // seconds
+	Stale         bool                 `json:"stale,omitempty"`
+	Age           int                  `json:"age,omitempty"` // seconds since the measurement
+}
+
+// latencyResponse holds milliseconds
+type latencyResponse struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// intervalResponse is one second of a round
+type intervalResponse struct {
+	Requests int `json:"requests"`
+	Errors   int `json:"errors"`
+}
+
+func wantsJson(req *http.Request) bool {
+	if req.FormValue("format") == "json" {
+		return true
+	}
+	return strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+func writeResult(w http.ResponseWriter, req *http.Request, result map[string]*benchmark.HostResult) {
+	for _, host := range result {
+		if !host.Done() {
+			// polling hint for hosts still being measured
+			w.Header().Set("Retry-After", strconv.Itoa(int(conf.GetConfig().SitesRetryAfter/time.Second)))
+			w.Header().Set("X-Partial-Result", "true")
+			break
+		}
+	}
+	if wantsJson(req) {
+		writeJsonResult(w, result)
+		return
+	}
+
+	keys := make([]string, 0, len(result))
+	for k := range result {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, hostName := range keys {
+		_, _ = fmt.Fprintf(w, "%3d: %s%s\n", result[hostName].Count, hostName, formatDetails(result[hostName]))
+	}
+}
+
+func writeJsonResult(w http.ResponseWriter, result map[string]*benchmark.HostResult) {
+	res := make(map[string]*hostResponse, len(result))
+	for hostName, host := range result {
+		res[hostName] = newHostResponse(host)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func newHostResponse(host *benchmark.HostResult) *hostResponse {
+	urls := host.Urls
+	if urls == nil {
+		urls = make([]string, 0)
+	}
+	errs := map[string]int(host.Errors)
+	if errs == nil {
+		errs = make(map[string]int)
+	}
+
+	return &hostResponse{
+		Recommended:   host.Count,
+		Status:        hostStatus(host.State),
+		Partial:       host.Partial,
+		Urls:          urls,
+		Confidence:    host.Confidence,
+		Throughput:    host.Throughput,
+		Errors:        errs,
+		Latency:       newLatencyResponse(host.Latency),
+		Timeline:      newTimelineResponse(host.Timeline),
+		RateLimitedAt: host.RateLimitedAt,
+		RetryAfter:    int(host.RetryAfter / time.Second),
+		Disallowed:    host.Disallowed,
+		CrawlDelay:    host.CrawlDelay.Seconds(),
+		Stale:         host.Stale,
+		Age:           int(host.Age / time.Second),
+	}
+}
+
+func newLatencyResponse(latency benchmark.LatencyStats) latencyResponse {
+	return latencyResponse{
+		Min: milliseconds(latency.Min),
+		Avg: milliseconds(latency.Avg),
+		P50: milliseconds(latency.P50),
+		P95: milliseconds(latency.P95),
+		P99: milliseconds(latency.P99),
+		Max: milliseconds(latency.Max),
+	}
+}
+
+func newTimelineResponse(timeline [][]benchmark.Interval) [][]intervalResponse {
+	res := make([][]intervalResponse, 0, len(timeline))
+	for _, round := range timeline {
+		intervals := make([]intervalResponse, 0, len(round))
+		for _, interval := range round {
+			intervals = append(intervals, intervalResponse{Requests: interval.Requests, Errors: interval.Errors})
+		}
+		res = append(res, intervals)
+	}
+	return res
+}
+
+func hostStatus(state string) string {
+	switch state {
+	case benchmark.StateUrlReady:
+		return statusReady
+	case benchmark.StateUrlInProgress:
+		return statusInProgress
+	case benchmark.StateUrlFailed:
+		return statusFailed
+	case benchmark.StateUrlQueued:
+		return statusQueued
+	}
+	return statusNotTested
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatDetails(res *benchmark.HostResult) string {
+	var details []string
+	switch res.State {
+	case benchmark.StateUrlQueued:
+		return " (queued)"
+	case benchmark.StateUrlNotTested:
+		if len(res.Disallowed) > 0 {
+			return " (not tested, disallowed by robots.txt)"
+		}
+		return " (not tested)"
+	case benchmark.StateUrlInProgress:
+		details = []string{"measuring, partial"}
+	default:
+		details = []string{"measured"}
+		if res.Stale {
+			details = []string{fmt.Sprintf("stale, measured %s ago", res.Age.Truncate(time.Second))}
+		}
+	}
+	details = append(details, fmt.Sprintf("confidence %.0f%%", res.Confidence*100))
+	if res.CrawlDelay > 0 {
+		details = append(details, fmt.Sprintf("crawl-delay %s", res.CrawlDelay))
+	}
+	if len(res.Disallowed) > 0 {
+		details = append(details, fmt.Sprintf("%d urls disallowed by robots.txt", len(res.Disallowed)))
+	}
+	if res.Throughput > 0 {
+		details = append(details, fmt.Sprintf("%.1f req/s", res.Throughput))
+	}
+	if res.RateLimitedAt > 0 {
+		limited := fmt.Sprintf("rate limited at %d", res.RateLimitedAt)
+		if res.RetryAfter > 0 {
+			limited += fmt.Sprintf(", retry after %s", res.RetryAfter)
+		}
+		details = append(details, limited)
+	}
+	if errs := formatErrors(res.Errors); errs != "" {
+		details = append(details, errs)
+	}
+
+	return " (" + strings.Join(details, "; ") + ")"
+}
+
+func formatErrors(errs benchmark.ErrorCounts) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	classes := make([]string, 0, len(errs))
+	for class := range errs {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	parts := make([]string, 0, len(classes))
+	for _, class := range classes {
+		parts = append(parts, fmt.Sprintf("%s: %d", class, errs[class]))
+	}
+
+	return "errors " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/serp"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// searchAdapter returns the adapter of the engine request param, yandex
+// is searched with the region, pages, numdoc and device request params.
+func searchAdapter(req *http.Request) (dataProvider.OverloadSitesToCheck, error) {
+	params := dataProvider.YandexParams()
+	for name, dst := range map[string]*int{"region": &params.Region, "pages": &params.Pages, "numdoc": &params.NumDoc} {
+		if value := req.FormValue(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s param: %s", name, value)
+			}
+			*dst = n
+		}
+	}
+	if device := req.FormValue("device"); device != "" {
+		params.Device = device
+	}
+
+	return dataProvider.GetSearchAdapter(req.FormValue("engine"), params)
+}
+
+// writeSearchError answers 503 with Retry-After when the search engine blocks us
+// and 502 when it answers with an unexpected status.
+func writeSearchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, serp.ErrSearchBlocked):
+		var retry *serp.RetryError
+		if errors.As(err, &retry) && retry.After > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.After.Seconds()))))
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprintf(w, "Search is blocked by captcha or rate limit, try later: %s", err.Error())
+	case errors.Is(err, serp.ErrSearchHTTPStatus):
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = fmt.Fprintf(w, "Search engine failed: %s", err.Error())
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "Search failed: %s", err.Error())
+	}
+}
@@ -1,14 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"lubyshev/go-site-benchmark/src/benchmark"
 	"lubyshev/go-site-benchmark/src/conf"
-	"lubyshev/go-site-benchmark/src/dataProvider"
 	"net/http"
-	"sort"
 	"strings"
 )
 
@@ -29,16 +28,29 @@ func Site(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sites, err := dataProvider.GetAdapter(dataProvider.DataProviderYandex).GetData(searchPhrase)
+	ctx, cancel := context.WithTimeout(req.Context(), conf.GetConfig().SitesRequestBudget)
+	defer cancel()
+
+	adapter, err := searchAdapter(req)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = fmt.Fprintf(w, "Yandex search failed: %s", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Invalid search params: %s", err.Error())
+		return
+	}
+	sites, err := adapter.GetData(ctx, searchPhrase)
+	if ctx.Err() == context.DeadlineExceeded {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		_, _ = fmt.Fprintf(w, "Search timed out")
+		return
+	}
+	if err != nil {
+		writeSearchError(w, err)
 		return
 	}
 
 	test := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
 
-	result, err := test.Benchmark(sites, conf.GetConfig().CacheTtl)
+	result, err := test.Benchmark(ctx, sites, conf.GetConfig().CacheTtl)
 	if err != nil || result == nil {
 		if err == nil {
 			err = errors.New("unexpected error")
@@ -48,14 +60,9 @@ func Site(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	keys := make([]string, 0, len(result))
-	for k, _ := range result {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	for _, hostName := range keys {
-		_, _ = fmt.Fprintf(w, "%3d: %s\n", result[hostName], hostName)
+	if sites.Partial {
+		w.Header().Set("X-Partial-Search", "true")
 	}
+	writeResult(w, req, result)
 	log.Printf("FINISH REQUEST FROM: %s\n===\n", req.RemoteAddr)
 }
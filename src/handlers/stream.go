@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/conf"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type stepEventResponse struct {
+	Time            time.Time            `json:"time"`
+	Host            string               `json:"host"`
+	Urls            []string             `json:"urls"`
+	Concurrency     int                  `json:"concurrency"`
+	Errors          int                  `json:"errors"`
+	Requests        int                  `json:"requests"`
+	FailedRequests  int                  `json:"failed_requests"`
+	ErrorClasses    map[string]int       `json:"error_classes"`
+	Latency         latencyResponse      `json:"latency"`
+	Timeline        [][]intervalResponse `json:"timeline"`
+	PrevStatus      string               `json:"prev_status"`
+	Status          string               `json:"status"`
+	Recommended     int                  `json:"recommended"`
+	NextConcurrency int                  `json:"next_concurrency,omitempty"`
+}
+
+// SiteStream serves GET /sites/stream?search= as text/event-stream: a "step"
+// event for every step of the searched hosts and a final "result" event.
+func SiteStream(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered in handlers.SiteStream()", r)
+		}
+	}()
+	log.Printf("START STREAM FROM: %s\n", req.RemoteAddr)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "Streaming is not supported")
+		return
+	}
+	searchPhrase := strings.Trim(req.FormValue("search"), " ")
+	if searchPhrase == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Empty search param")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), conf.GetConfig().SitesStreamTimeout)
+	defer cancel()
+
+	adapter, err := searchAdapter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "Invalid search params: %s", err.Error())
+		return
+	}
+	sites, err := adapter.GetData(ctx, searchPhrase)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+
+	test := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	stepEvents, unsubscribe := test.Subscribe()
+	defer unsubscribe()
+
+	if sites.Partial {
+		w.Header().Set("X-Partial-Search", "true")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	type benchmarkResult struct {
+		result map[string]*benchmark.HostResult
+		err    error
+	}
+	chResult := make(chan benchmarkResult, 1)
+	go func() {
+		result, err := test.Benchmark(ctx, sites, conf.GetConfig().CacheTtl)
+		chResult <- benchmarkResult{result, err}
+	}()
+
+	writeStep := func(e *benchmark.StepEvent) {
+		if _, ok := sites.Items[e.Host]; ok {
+			writeEvent(w, "step", newStepEventResponse(e))
+			flusher.Flush()
+		}
+	}
+	for {
+		select {
+		case e := <-stepEvents:
+			writeStep(e)
+		case res := <-chResult:
+			// the steps published before the result go first
+			for drained := false; !drained; {
+				select {
+				case e := <-stepEvents:
+					writeStep(e)
+				default:
+					drained = true
+				}
+			}
+			if res.err != nil {
+				writeEvent(w, "error", res.err.Error())
+			} else {
+				hosts := make(map[string]*hostResponse, len(res.result))
+				for hostName, host := range res.result {
+					hosts[hostName] = newHostResponse(host)
+				}
+				writeEvent(w, "result", hosts)
+			}
+			flusher.Flush()
+			log.Printf("FINISH STREAM FROM: %s\n===\n", req.RemoteAddr)
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("ERROR: %s\n", err.Error())
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+func newStepEventResponse(e *benchmark.StepEvent) *stepEventResponse {
+	errs := map[string]int(e.ErrorClasses)
+	if errs == nil {
+		errs = make(map[string]int)
+	}
+	return &stepEventResponse{
+		Time:            e.Time,
+		Host:            e.Host,
+		Urls:            e.Urls,
+		Concurrency:     e.Concurrency,
+		Errors:          e.Errors,
+		Requests:        e.Requests,
+		FailedRequests:  e.FailedRequests,
+		ErrorClasses:    errs,
+		Latency:         newLatencyResponse(e.Latency),
+		Timeline:        newTimelineResponse(e.Timeline),
+		PrevStatus:      hostStatus(e.PrevState),
+		Status:          hostStatus(e.State),
+		Recommended:     e.Count,
+		NextConcurrency: e.NextConcurrency,
+	}
+}
@@ -0,0 +1,291 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/yandex"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateCancelled = "cancelled"
+	StateFailed    = "failed"
+)
+
+var (
+	ErrNotFound      = errors.New("job not found")
+	ErrEmptyJob      = errors.New("search phrase or hosts required")
+	ErrAlreadyFinish = errors.New("job already finished")
+	ErrInvalidSearch = errors.New("unknown search engine or invalid search params")
+	ErrInvalidHosts  = errors.New("invalid hosts")
+)
+
+type Job struct {
+	Id         string
+	Search     string
+	Engine     string
+	Yandex     yandex.SearchParams
+	State      string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt time.Time
+	Sites      *dataProvider.HostsToCheck
+	Results    map[string]*benchmark.HostResult
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the runner has returned
+}
+
+type Manager struct {
+	jobs    map[string]*Job
+	order   []string
+	mx      sync.RWMutex
+	history int
+	timeout time.Duration
+	ttl     time.Duration
+}
+
+var manager *Manager
+var once sync.Once
+
+func GetManager() *Manager {
+	once.Do(func() {
+		manager = new(Manager)
+		manager.jobs = make(map[string]*Job)
+		manager.order = make([]string, 0)
+	})
+	return manager
+}
+
+// Configure sets the number of kept finished jobs, the max job duration
+// and the ttl of the benchmark results.
+func (m *Manager) Configure(history int, timeout time.Duration, ttl time.Duration) {
+	defer m.mx.Unlock()
+	m.mx.Lock()
+	m.history = history
+	m.timeout = timeout
+	m.ttl = ttl
+}
+
+// Create starts a job for the search phrase on the engine (see
+// dataProvider.GetSearchAdapter), or for the hosts if the phrase is empty.
+// Hosts are urls or host names grouped by root domain like an explicit url list.
+func (m *Manager) Create(search string, engine string, params yandex.SearchParams, hosts []string) (*Job, error) {
+	search = strings.TrimSpace(search)
+	var sites *dataProvider.HostsToCheck
+	if search == "" {
+		var err error
+		sites, err = dataProvider.ParseUrls(hosts)
+		if err == dataProvider.ErrEmptyList {
+			return nil, ErrEmptyJob
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHosts, err.Error())
+		}
+	} else if _, err := dataProvider.GetSearchAdapter(engine, params); err != nil {
+		return nil, ErrInvalidSearch
+	}
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mx.Lock()
+	job := &Job{
+		Id:        id,
+		Search:    search,
+		Engine:    engine,
+		Yandex:    params,
+		State:     StatePending,
+		CreatedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	job.ctx, job.cancel = context.WithTimeout(context.Background(), m.timeout)
+	m.jobs[id] = job
+	m.order = append(m.order, id)
+	m.cleanup()
+	m.mx.Unlock()
+
+	go m.run(job, sites)
+
+	return m.Get(id)
+}
+
+// Get returns a copy of the job with the current results.
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mx.RLock()
+	job, ok := m.jobs[id]
+	if !ok {
+		m.mx.RUnlock()
+		return nil, ErrNotFound
+	}
+	res := job.copy()
+	m.mx.RUnlock()
+
+	if res.State == StateRunning && res.Sites != nil {
+		res.Results = overloadTest().Progress(res.Sites)
+	}
+
+	return res, nil
+}
+
+// List returns copies of the jobs, the newest first.
+func (m *Manager) List() []*Job {
+	m.mx.RLock()
+	ids := make([]string, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		ids = append(ids, m.order[i])
+	}
+	m.mx.RUnlock()
+
+	res := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		if job, err := m.Get(id); err == nil {
+			res = append(res, job)
+		}
+	}
+
+	return res
+}
+
+// Cancel stops the job and returns when its partial results are kept.
+func (m *Manager) Cancel(id string) error {
+	m.mx.Lock()
+	job, ok := m.jobs[id]
+	if !ok {
+		m.mx.Unlock()
+		return ErrNotFound
+	}
+	if job.State != StatePending && job.State != StateRunning {
+		m.mx.Unlock()
+		return ErrAlreadyFinish
+	}
+	job.State = StateCancelled
+	job.FinishedAt = time.Now()
+	// the job stops waiting, the runner stops measuring its hosts
+	job.cancel()
+	m.mx.Unlock()
+	<-job.done
+	log.Printf("job %s cancelled", id)
+
+	return nil
+}
+
+func (m *Manager) run(job *Job, sites *dataProvider.HostsToCheck) {
+	defer close(job.done)
+	m.mx.Lock()
+	if job.State != StatePending {
+		m.mx.Unlock()
+		return
+	}
+	job.State = StateRunning
+	m.mx.Unlock()
+
+	var err error
+	if job.Search != "" {
+		var adapter dataProvider.OverloadSitesToCheck
+		adapter, err = dataProvider.GetSearchAdapter(job.Engine, job.Yandex)
+		if err == nil {
+			sites, err = adapter.GetData(job.ctx, job.Search)
+		}
+	}
+	if err != nil {
+		m.finish(job, nil, nil, err)
+		return
+	}
+
+	m.mx.Lock()
+	job.Sites = sites
+	m.mx.Unlock()
+
+	res, err := overloadTest().Benchmark(job.ctx, sites, m.ttl)
+	if job.ctx.Err() == context.Canceled {
+		// the hosts measured for other requests too are not stopped
+		res = overloadTest().Cancel(sites)
+	}
+	m.finish(job, sites, res, err)
+}
+
+func (m *Manager) finish(job *Job, sites *dataProvider.HostsToCheck, res map[string]*benchmark.HostResult, err error) {
+	defer m.mx.Unlock()
+	m.mx.Lock()
+	if job.State == StateCancelled {
+		// the partial results
+		job.Sites = sites
+		job.Results = res
+		return
+	}
+	job.FinishedAt = time.Now()
+	job.Sites = sites
+	job.Results = res
+	switch {
+	case err != nil:
+		job.State = StateFailed
+		job.Error = err.Error()
+	case job.ctx.Err() == context.DeadlineExceeded:
+		job.State = StateFailed
+		job.Error = "job timed out"
+	default:
+		job.State = StateDone
+	}
+	job.cancel()
+	log.Printf("job %s finished: %s", job.Id, job.State)
+}
+
+// cleanup forgets the oldest finished jobs over the history limit.
+func (m *Manager) cleanup() {
+	extra := len(m.order) - m.history
+	if extra <= 0 {
+		return
+	}
+	order := make([]string, 0, len(m.order))
+	for _, id := range m.order {
+		job := m.jobs[id]
+		if extra > 0 && job.State != StatePending && job.State != StateRunning {
+			delete(m.jobs, id)
+			extra--
+			continue
+		}
+		order = append(order, id)
+	}
+	m.order = order
+}
+
+func (j *Job) copy() *Job {
+	return &Job{
+		Id:         j.Id,
+		Search:     j.Search,
+		Engine:     j.Engine,
+		Yandex:     j.Yandex,
+		State:      j.State,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+		Sites:      j.Sites,
+		Results:    j.Results,
+	}
+}
+
+func overloadTest() benchmark.OverloadTest {
+	return benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+}
+
+func newId() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,213 @@
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRobotsSize = 512 * 1024
+
+type Robots struct {
+	Sitemaps []string
+	groups   []*group
+}
+
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// Fetch gets robots.txt of the site, e.g. "https://example.com".
+// A missing robots.txt (4xx) allows everything, an unreachable
+// one (5xx, network errors) is an error.
+func Fetch(ctx context.Context, site string, userAgent string) (*Robots, error) {
+	body, err := FetchBody(ctx, site, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(body), nil
+}
+
+// FetchBody gets robots.txt of the site like Fetch, a missing one is empty.
+func FetchBody(ctx context.Context, site string, userAgent string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(site, "/")+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return []byte{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("robots.txt of %s: unexpected status %d", site, resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+}
+
+func Parse(body []byte) *Robots {
+	res := &Robots{Sitemaps: make([]string, 0)}
+	var current *group
+	// consecutive user-agent lines share the group
+	agentsOpen := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		key, value, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		switch key {
+		case "sitemap":
+			res.Sitemaps = append(res.Sitemaps, value)
+			continue
+		case "user-agent":
+			if !agentsOpen {
+				current = &group{}
+				res.groups = append(res.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			agentsOpen = true
+			continue
+		}
+		agentsOpen = false
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			if value != "" {
+				current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if delay, err := strconv.ParseFloat(value, 64); err == nil && delay > 0 {
+				current.crawlDelay = time.Duration(delay * float64(time.Second))
+			}
+		}
+	}
+
+	return res
+}
+
+// Allowed reports whether the path (with the query) may be fetched by the agent:
+// the longest matching rule wins, allow wins a tie.
+func (r *Robots) Allowed(userAgent string, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	allowed, matched := true, -1
+	for _, g := range r.agentGroups(userAgent) {
+		for _, rl := range g.rules {
+			if !match(rl.pattern, path) {
+				continue
+			}
+			if l := len(rl.pattern); l > matched || (l == matched && rl.allow) {
+				allowed, matched = rl.allow, l
+			}
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay for the agent, zero if not set.
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var res time.Duration
+	for _, g := range r.agentGroups(userAgent) {
+		if g.crawlDelay > res {
+			res = g.crawlDelay
+		}
+	}
+
+	return res
+}
+
+// agentGroups returns the groups of the product token of the agent
+// ("Bot/1.0" -> "bot"), or the "*" groups if there are none.
+func (r *Robots) agentGroups(userAgent string) []*group {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	res, common := make([]*group, 0), make([]*group, 0)
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				common = append(common, g)
+				break
+			}
+			if token != "" && agent == token {
+				res = append(res, g)
+				break
+			}
+		}
+	}
+	if len(res) == 0 {
+		return common
+	}
+
+	return res
+}
+
+// match supports "*" wildcards and the "$" end anchor of the pattern.
+func match(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		j := strings.Index(path[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
+
+func parseLine(line string) (key string, value string, ok bool) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	i := strings.IndexByte(line, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	key = strings.ToLower(strings.TrimSpace(line[:i]))
+	value = strings.TrimSpace(line[i+1:])
+
+	return key, value, key != ""
+}
@@ -0,0 +1,109 @@
+package serp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSearchBlocked is returned for captcha and rate limit responses
+	ErrSearchBlocked = errors.New("search blocked")
+	// ErrSearchHTTPStatus is returned for other non-200 responses
+	ErrSearchHTTPStatus = errors.New("search unexpected http status")
+)
+
+// RetryError is a blocked search with a known time to retry after,
+// e.g. the backoff of the engine.
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type ResponseStruct struct {
+	Items []ResponseItem
+	// the search was blocked on a later page, Items hold the pages before it
+	Partial bool
+}
+
+type ResponseItem struct {
+	Host string
+	Url  string
+}
+
+// Engine describes the captcha pages of a search engine: the paths
+// the SERP is redirected to and the lower case markers of their bodies.
+type Engine struct {
+	Name         string
+	CaptchaPaths []string
+	Markers      [][]byte
+}
+
+// Fetch gets the SERP page and checks it by CheckSearchResponse.
+func (e *Engine) Fetch(ctx context.Context, pageUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.CheckSearchResponse(resp, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// CheckSearchResponse detects captcha pages (including redirects to them),
+// rate limits and unexpected statuses of the SERP response.
+func (e *Engine) CheckSearchResponse(resp *http.Response, body []byte) error {
+	if resp.Request != nil && resp.Request.URL != nil {
+		for _, path := range e.CaptchaPaths {
+			if strings.HasPrefix(resp.Request.URL.Path, path) {
+				return fmt.Errorf("%w: %s: redirected to captcha", ErrSearchBlocked, e.Name)
+			}
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return fmt.Errorf("%w: %s: http status %d", ErrSearchBlocked, e.Name, resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: %s: %d", ErrSearchHTTPStatus, e.Name, resp.StatusCode)
+	}
+	lower := bytes.ToLower(body)
+	for _, marker := range e.Markers {
+		if bytes.Contains(lower, marker) {
+			return fmt.Errorf("%w: %s: captcha page", ErrSearchBlocked, e.Name)
+		}
+	}
+	return nil
+}
+
+// IsDomainOf reports whether the host is the domain or its subdomain,
+// so "notbing.com" is not taken for "bing.com".
+func IsDomainOf(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,120 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// sitemaps are limited by 50MB uncompressed
+const maxSitemapSize = 50 * 1024 * 1024
+
+type document struct {
+	XMLName  xml.Name
+	Urls     []location `xml:"url"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc string `xml:"loc"`
+}
+
+// Parse returns page urls of an urlset and nested sitemaps of a sitemap index.
+// Gzipped sitemaps are unpacked.
+func Parse(body []byte) (urls []string, sitemaps []string, err error) {
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err = ioutil.ReadAll(io.LimitReader(zr, maxSitemapSize))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	doc := document{}
+	if err = xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap: %v", err)
+	}
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+	default:
+		return nil, nil, fmt.Errorf("invalid sitemap: unexpected root <%s>", doc.XMLName.Local)
+	}
+	urls = make([]string, 0, len(doc.Urls))
+	for _, u := range doc.Urls {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			urls = append(urls, loc)
+		}
+	}
+	sitemaps = make([]string, 0, len(doc.Sitemaps))
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return urls, sitemaps, nil
+}
+
+// Collect walks the sitemaps and nested sitemap indexes, fetching
+// no more than maxFiles files, and returns the found page urls.
+func Collect(ctx context.Context, sitemaps []string, maxFiles int) ([]string, error) {
+	res := make([]string, 0)
+	seen := make(map[string]struct{})
+	queue := append([]string{}, sitemaps...)
+	var lastErr error
+	for fetched := 0; len(queue) > 0 && fetched < maxFiles; {
+		sitemapUrl := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[sitemapUrl]; ok {
+			continue
+		}
+		seen[sitemapUrl] = struct{}{}
+		fetched++
+
+		body, err := fetch(ctx, sitemapUrl)
+		if err == nil {
+			var urls, nested []string
+			urls, nested, err = Parse(body)
+			res = append(res, urls...)
+			queue = append(queue, nested...)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+		}
+	}
+	if len(res) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return res, nil
+}
+
+func fetch(ctx context.Context, sitemapUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s: unexpected status %d", sitemapUrl, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+}
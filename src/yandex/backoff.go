@@ -0,0 +1,60 @@
+package yandex
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBackoffMin = 30 * time.Second
+	defaultBackoffMax = 10 * time.Minute
+)
+
+// backoff doubles the pause after every consecutive block, a successful search resets it.
+type backoff struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+	until   time.Time
+	mx      sync.Mutex
+}
+
+var searchBackoff = &backoff{min: defaultBackoffMin, max: defaultBackoffMax}
+
+// ConfigureBackoff sets the pause after the first block and its upper limit.
+func ConfigureBackoff(min time.Duration, max time.Duration) {
+	defer searchBackoff.mx.Unlock()
+	searchBackoff.mx.Lock()
+	searchBackoff.min = min
+	searchBackoff.max = max
+}
+
+// BlockedFor returns the rest of the backoff, zero if searches are allowed.
+func BlockedFor() time.Duration {
+	defer searchBackoff.mx.Unlock()
+	searchBackoff.mx.Lock()
+	if d := time.Until(searchBackoff.until); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// blocked starts the next pause and returns its duration.
+func (b *backoff) blocked() time.Duration {
+	defer b.mx.Unlock()
+	b.mx.Lock()
+	if b.current == 0 {
+		b.current = b.min
+	} else if b.current *= 2; b.current > b.max {
+		b.current = b.max
+	}
+	b.until = time.Now().Add(b.current)
+	return b.current
+}
+
+func (b *backoff) reset() {
+	defer b.mx.Unlock()
+	b.mx.Lock()
+	b.current = 0
+	b.until = time.Time{}
+}
@@ -2,49 +2,140 @@ package yandex
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"io/ioutil"
-	"net/http"
+	"log"
+	"lubyshev/go-site-benchmark/src/domain"
+	"lubyshev/go-site-benchmark/src/serp"
 	"net/url"
 	"strings"
+	"time"
 )
 
-const searchYandexTemplate = "https://yandex.ru/search/touch/?service=www.yandex&ui=webmobileapp.yandex&numdoc=50&lr=213&p=0&text=%s"
+const (
+	searchYandexTouchTemplate   = "https://yandex.ru/search/touch/?service=www.yandex&ui=webmobileapp.yandex&numdoc=%d&lr=%d&p=%d&text=%s"
+	searchYandexDesktopTemplate = "https://yandex.ru/search/?numdoc=%d&lr=%d&p=%d&text=%s"
+)
 
-type ResponseStruct struct {
-	Items []ResponseItem
+// engine recognizes the captcha pages yandex shows to robots
+var engine = &serp.Engine{
+	Name:         "yandex",
+	CaptchaPaths: []string{"/showcaptcha", "/checkcaptcha"},
+	Markers:      [][]byte{[]byte("showcaptcha"), []byte("checkcaptcha"), []byte("captcha__image")},
 }
 
-type ResponseItem struct {
-	Host string
-	Url  string
+const (
+	DeviceTouch   = "touch"
+	DeviceDesktop = "desktop"
+)
+
+const (
+	maxPages  = 10
+	maxNumDoc = 50
+)
+
+// SearchParams of the SERP: Region is the yandex region id ("lr", 213 is Moscow),
+// Pages of NumDoc results each are fetched and merged.
+type SearchParams struct {
+	Region int
+	Pages  int
+	NumDoc int
+	Device string
 }
 
-func GetYandexSearchResult(searchPhrase string) (res *ResponseStruct, err error) {
-	resp, err := http.Get(fmt.Sprintf(searchYandexTemplate, url.QueryEscape(searchPhrase)))
-	if err != nil {
+func (p SearchParams) Validate() error {
+	if p.Region <= 0 {
+		return fmt.Errorf("invalid yandex region: %d", p.Region)
+	}
+	if p.Pages < 1 || p.Pages > maxPages {
+		return fmt.Errorf("invalid yandex pages: %d, 1..%d expected", p.Pages, maxPages)
+	}
+	if p.NumDoc < 1 || p.NumDoc > maxNumDoc {
+		return fmt.Errorf("invalid yandex numdoc: %d, 1..%d expected", p.NumDoc, maxNumDoc)
+	}
+	if p.Device != DeviceTouch && p.Device != DeviceDesktop {
+		return fmt.Errorf("invalid yandex device: %s", p.Device)
+	}
+	return nil
+}
+
+func (p SearchParams) String() string {
+	return fmt.Sprintf("lr=%d&pages=%d&numdoc=%d&device=%s", p.Region, p.Pages, p.NumDoc, p.Device)
+}
+
+func (p SearchParams) pageUrl(searchPhrase string, page int) string {
+	template := searchYandexTouchTemplate
+	if p.Device == DeviceDesktop {
+		template = searchYandexDesktopTemplate
+	}
+	return fmt.Sprintf(template, p.NumDoc, p.Region, page, url.QueryEscape(searchPhrase))
+}
+
+// GetYandexSearchResult fetches the pages one by one and merges their items,
+// the first occurrence of an url is kept. After a block (see serp.Engine)
+// searches fail with serp.ErrSearchBlocked until the backoff is over,
+// both are returned as serp.RetryError. A block after the first page
+// starts the backoff too, but the pages fetched before it are returned as a partial result.
+func GetYandexSearchResult(ctx context.Context, searchPhrase string, params SearchParams) (res *serp.ResponseStruct, err error) {
+	if err = params.Validate(); err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	response, err := ioutil.ReadAll(resp.Body)
+	if d := BlockedFor(); d > 0 {
+		return nil, &serp.RetryError{
+			Err:   fmt.Errorf("%w: yandex: backoff, retry after %s", serp.ErrSearchBlocked, d.Round(time.Second)),
+			After: d,
+		}
+	}
+	res = &serp.ResponseStruct{Items: make([]serp.ResponseItem, 0)}
+	seen := make(map[string]struct{})
+	for page := 0; page < params.Pages; page++ {
+		pageRes, err := getYandexSearchPage(ctx, params.pageUrl(searchPhrase, page))
+		if errors.Is(err, serp.ErrSearchBlocked) {
+			d := searchBackoff.blocked()
+			if page > 0 {
+				log.Printf("ERROR: yandex page %d is blocked, the search is partial: %s\n", page, err.Error())
+				res.Partial = true
+				return res, nil
+			}
+			return nil, &serp.RetryError{Err: err, After: d}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(pageRes.Items) == 0 {
+			break
+		}
+		for _, item := range pageRes.Items {
+			if _, ok := seen[item.Url]; !ok {
+				seen[item.Url] = struct{}{}
+				res.Items = append(res.Items, item)
+			}
+		}
+	}
+	searchBackoff.reset()
+	return res, nil
+}
+
+func getYandexSearchPage(ctx context.Context, pageUrl string) (res *serp.ResponseStruct, err error) {
+	body, err := engine.Fetch(ctx, pageUrl)
 	if err != nil {
 		return nil, err
 	}
-	return parseYandexResponse(response)
+	return parseYandexResponse(body)
 }
 
-func parseYandexResponse(response []byte) (sites *ResponseStruct, err error) {
-	res := ResponseStruct{Items: make([]ResponseItem, 0)}
+func parseYandexResponse(response []byte) (sites *serp.ResponseStruct, err error) {
+	res := serp.ResponseStruct{Items: make([]serp.ResponseItem, 0)}
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response))
 	if err != nil {
 		err = fmt.Errorf("can't create parser for body: %v", err)
 		return
 	}
-	items := doc.Find("div.serp-item")
+	// div.serp-item on touch, li.serp-item on desktop
+	items := doc.Find(".serp-item")
 	items.Each(func(i int, selection *goquery.Selection) {
 		_, aExists := selection.Attr("data-fast-name")
 		_, cidExists := selection.Attr("data-cid")
@@ -72,8 +163,8 @@ func parseYandexResponse(response []byte) (sites *ResponseStruct, err error) {
 					return
 				}
 
-				res.Items = append(res.Items, ResponseItem{
-					Host: getRootDomain(u.Host),
+				res.Items = append(res.Items, serp.ResponseItem{
+					Host: domain.GetRootDomain(u.Hostname()),
 					Url:  urlStr,
 				})
 			}
@@ -81,716 +172,3 @@ func parseYandexResponse(response []byte) (sites *ResponseStruct, err error) {
 	})
 	return &res, nil
 }
-
-func getRootDomain(domain string) string {
-	domain = strings.ToLower(domain)
-
-	parts := strings.Split(domain, ".")
-	if len(parts) < 3 {
-		return domain
-	}
-
-	if _, ok := tlds[strings.Join(parts[len(parts)-2:], ".")]; ok {
-		return strings.Join(parts[len(parts)-3:], ".")
-	}
-
-	return strings.Join(parts[len(parts)-2:], ".")
-}
-
-var tlds = map[string]struct{}{
-	"рф":             {},
-	"com.ru":         {},
-	"exnet.su":       {},
-	"net.ru":         {},
-	"org.ru":         {},
-	"pp.ru":          {},
-	"ru":             {},
-	"ru.net":         {},
-	"su":             {},
-	"aero":           {},
-	"asia":           {},
-	"biz":            {},
-	"com":            {},
-	"info":           {},
-	"mobi":           {},
-	"name":           {},
-	"net":            {},
-	"org":            {},
-	"pro":            {},
-	"tel":            {},
-	"travel":         {},
-	"xxx":            {},
-	"adygeya.ru":     {},
-	"adygeya.su":     {},
-	"arkhangelsk.su": {},
-	"balashov.su":    {},
-	"bashkiria.ru":   {},
-	"bashkiria.su":   {},
-	"bir.ru":         {},
-	"bryansk.su":     {},
-	"cbg.ru":         {},
-	"dagestan.ru":    {},
-	"dagestan.su":    {},
-	"grozny.ru":      {},
-	"ivanovo.su":     {},
-	"kalmykia.ru":    {},
-	"kalmykia.su":    {},
-	"kaluga.su":      {},
-	"karelia.su":     {},
-	"khakassia.su":   {},
-	"krasnodar.su":   {},
-	"kurgan.su":      {},
-	"lenug.su":       {},
-	"marine.ru":      {},
-	"mordovia.ru":    {},
-	"mordovia.su":    {},
-	"msk.ru":         {},
-	"msk.su":         {},
-	"murmansk.su":    {},
-	"mytis.ru":       {},
-	"nalchik.ru":     {},
-	"nalchik.su":     {},
-	"nov.ru":         {},
-	"nov.su":         {},
-	"obninsk.su":     {},
-	"penza.su":       {},
-	"pokrovsk.su":    {},
-	"pyatigorsk.ru":  {},
-	"sochi.su":       {},
-	"spb.ru":         {},
-	"spb.su":         {},
-	"togliatti.su":   {},
-	"troitsk.su":     {},
-	"tula.su":        {},
-	"tuva.su":        {},
-	"vladikavkaz.ru": {},
-	"vladikavkaz.su": {},
-	"vladimir.ru":    {},
-	"vladimir.su":    {},
-	"vologda.su":     {},
-	"ad":             {},
-	"ae":             {},
-	"af":             {},
-	"ai":             {},
-	"al":             {},
-	"am":             {},
-	"aq":             {},
-	"as":             {},
-	"at":             {},
-	"aw":             {},
-	"ax":             {},
-	"az":             {},
-	"ba":             {},
-	"be":             {},
-	"bg":             {},
-	"bh":             {},
-	"bi":             {},
-	"bj":             {},
-	"bm":             {},
-	"bo":             {},
-	"bs":             {},
-	"bt":             {},
-	"ca":             {},
-	"cc":             {},
-	"cd":             {},
-	"cf":             {},
-	"cg":             {},
-	"ch":             {},
-	"ci":             {},
-	"cl":             {},
-	"cm":             {},
-	"cn":             {},
-	"co":             {},
-	"co.ao":          {},
-	"co.bw":          {},
-	"co.ck":          {},
-	"co.fk":          {},
-	"co.id":          {},
-	"co.il":          {},
-	"co.in":          {},
-	"co.ke":          {},
-	"co.ls":          {},
-	"co.mz":          {},
-	"co.no":          {},
-	"co.nz":          {},
-	"co.th":          {},
-	"co.tz":          {},
-	"co.uk":          {},
-	"co.uz":          {},
-	"co.za":          {},
-	"co.zm":          {},
-	"co.zw":          {},
-	"com.ai":         {},
-	"com.ar":         {},
-	"com.au":         {},
-	"com.bd":         {},
-	"com.bn":         {},
-	"com.br":         {},
-	"com.cn":         {},
-	"com.cy":         {},
-	"com.eg":         {},
-	"com.et":         {},
-	"com.fj":         {},
-	"com.gh":         {},
-	"com.gn":         {},
-	"com.gt":         {},
-	"com.gu":         {},
-	"com.hk":         {},
-	"com.jm":         {},
-	"com.kh":         {},
-	"com.kw":         {},
-	"com.lb":         {},
-	"com.lr":         {},
-	"com.mt":         {},
-	"com.mv":         {},
-	"com.ng":         {},
-	"com.ni":         {},
-	"com.np":         {},
-	"com.nr":         {},
-	"com.om":         {},
-	"com.pa":         {},
-	"com.pl":         {},
-	"com.py":         {},
-	"com.qa":         {},
-	"com.sa":         {},
-	"com.sb":         {},
-	"com.sg":         {},
-	"com.sv":         {},
-	"com.sy":         {},
-	"com.tr":         {},
-	"com.tw":         {},
-	"com.ua":         {},
-	"com.uy":         {},
-	"com.ve":         {},
-	"com.vi":         {},
-	"com.vn":         {},
-	"com.ye":         {},
-	"cr":             {},
-	"cu":             {},
-	"cx":             {},
-	"cz":             {},
-	"de":             {},
-	"dj":             {},
-	"dk":             {},
-	"dm":             {},
-	"do":             {},
-	"dz":             {},
-	"ec":             {},
-	"ee":             {},
-	"es":             {},
-	"eu":             {},
-	"fi":             {},
-	"fo":             {},
-	"fr":             {},
-	"ga":             {},
-	"gd":             {},
-	"ge":             {},
-	"gf":             {},
-	"gg":             {},
-	"gi":             {},
-	"gl":             {},
-	"gm":             {},
-	"gp":             {},
-	"gr":             {},
-	"gs":             {},
-	"gy":             {},
-	"hk":             {},
-	"hm":             {},
-	"hn":             {},
-	"hr":             {},
-	"ht":             {},
-	"hu":             {},
-	"ie":             {},
-	"im":             {},
-	"in":             {},
-	"in.ua":          {},
-	"io ":            {},
-	"ir":             {},
-	"is":             {},
-	"it":             {},
-	"je":             {},
-	"jo":             {},
-	"jp":             {},
-	"kg":             {},
-	"ki":             {},
-	"kiev.ua":        {},
-	"kn":             {},
-	"kr":             {},
-	"ky":             {},
-	"kz":             {},
-	"li":             {},
-	"lk":             {},
-	"lt":             {},
-	"lu":             {},
-	"lv":             {},
-	"ly":             {},
-	"ma":             {},
-	"mc":             {},
-	"md":             {},
-	"me.uk":          {},
-	"mg":             {},
-	"mk":             {},
-	"mo":             {},
-	"mp":             {},
-	"ms":             {},
-	"mt":             {},
-	"mu":             {},
-	"mw":             {},
-	"mx":             {},
-	"my":             {},
-	"na":             {},
-	"nc":             {},
-	"net.cn":         {},
-	"nf":             {},
-	"ng":             {},
-	"nl":             {},
-	"no":             {},
-	"nu":             {},
-	"nz":             {},
-	"org.cn":         {},
-	"org.uk":         {},
-	"pe":             {},
-	"ph":             {},
-	"pk":             {},
-	"pl":             {},
-	"pn":             {},
-	"pr":             {},
-	"ps":             {},
-	"pt":             {},
-	"re":             {},
-	"ro":             {},
-	"rs":             {},
-	"rw":             {},
-	"sd":             {},
-	"se":             {},
-	"sg":             {},
-	"si":             {},
-	"sk":             {},
-	"sl":             {},
-	"sm":             {},
-	"sn":             {},
-	"so":             {},
-	"sr":             {},
-	"st":             {},
-	"sz":             {},
-	"tc":             {},
-	"td":             {},
-	"tg":             {},
-	"tj":             {},
-	"tk":             {},
-	"tl":             {},
-	"tm":             {},
-	"tn":             {},
-	"to":             {},
-	"tt":             {},
-	"tw":             {},
-	"ua":             {},
-	"ug":             {},
-	"uk":             {},
-	"us":             {},
-	"vc":             {},
-	"vg":             {},
-	"vn":             {},
-	"vu":             {},
-	"ws":             {},
-	"academy":        {},
-	"accountant":     {},
-	"accountants":    {},
-	"actor":          {},
-	"adult":          {},
-	"africa":         {},
-	"agency":         {},
-	"airforce":       {},
-	"apartments":     {},
-	"app":            {},
-	"army":           {},
-	"art":            {},
-	"associates":     {},
-	"attorney":       {},
-	"auction":        {},
-	"audio":          {},
-	"auto":           {},
-	"band":           {},
-	"bank":           {},
-	"bar":            {},
-	"bargains":       {},
-	"bayern":         {},
-	"beer":           {},
-	"berlin":         {},
-	"best":           {},
-	"bet":            {},
-	"bid":            {},
-	"bike":           {},
-	"bingo":          {},
-	"bio":            {},
-	"black":          {},
-	"blackfriday":    {},
-	"blog":           {},
-	"blue":           {},
-	"boutique":       {},
-	"broker":         {},
-	"brussels":       {},
-	"build":          {},
-	"builders":       {},
-	"business":       {},
-	"buzz":           {},
-	"cab":            {},
-	"cafe":           {},
-	"cam":            {},
-	"camera":         {},
-	"camp":           {},
-	"capital":        {},
-	"car":            {},
-	"cards":          {},
-	"care":           {},
-	"career":         {},
-	"careers":        {},
-	"cars":           {},
-	"casa ":          {},
-	"cash":           {},
-	"casino":         {},
-	"cat":            {},
-	"catering":       {},
-	"center":         {},
-	"ceo":            {},
-	"charity":        {},
-	"chat":           {},
-	"cheap":          {},
-	"christmas":      {},
-	"church":         {},
-	"city":           {},
-	"claims":         {},
-	"cleaning":       {},
-	"click":          {},
-	"clinic":         {},
-	"clothing":       {},
-	"cloud":          {},
-	"club":           {},
-	"coach":          {},
-	"codes":          {},
-	"coffee":         {},
-	"college":        {},
-	"cologne":        {},
-	"community":      {},
-	"company":        {},
-	"computer":       {},
-	"condos":         {},
-	"construction":   {},
-	"consulting":     {},
-	"contractors":    {},
-	"cooking":        {},
-	"cool":           {},
-	"coop":           {},
-	"country":        {},
-	"coupons":        {},
-	"courses":        {},
-	"credit":         {},
-	"creditcard":     {},
-	"cricket":        {},
-	"cruises":        {},
-	"dance":          {},
-	"date":           {},
-	"dating":         {},
-	"deals":          {},
-	"degree":         {},
-	"delivery":       {},
-	"democrat":       {},
-	"dental":         {},
-	"dentist":        {},
-	"desi":           {},
-	"design":         {},
-	"diamonds":       {},
-	"diet":           {},
-	"digital":        {},
-	"direct":         {},
-	"directory":      {},
-	"discount":       {},
-	"doctor":         {},
-	"dog":            {},
-	"domains":        {},
-	"download":       {},
-	"earth":          {},
-	"education":      {},
-	"email":          {},
-	"energy":         {},
-	"engineer":       {},
-	"engineering":    {},
-	"enterprises":    {},
-	"equipment":      {},
-	"estate":         {},
-	"events":         {},
-	"exchange":       {},
-	"expert":         {},
-	"exposed":        {},
-	"express":        {},
-	"fail":           {},
-	"faith":          {},
-	"family":         {},
-	"fans":           {},
-	"farm":           {},
-	"fashion":        {},
-	"film":           {},
-	"finance":        {},
-	"financial":      {},
-	"fish":           {},
-	"fishing":        {},
-	"fit":            {},
-	"fitness":        {},
-	"flights":        {},
-	"florist":        {},
-	"flowers":        {},
-	"fm":             {},
-	"football":       {},
-	"forex":          {},
-	"forsale":        {},
-	"foundation":     {},
-	"fun":            {},
-	"fund":           {},
-	"furniture":      {},
-	"futbol":         {},
-	"fyi":            {},
-	"gallery":        {},
-	"game":           {},
-	"games":          {},
-	"garden":         {},
-	"gent":           {},
-	"gift":           {},
-	"gifts":          {},
-	"gives":          {},
-	"glass":          {},
-	"global":         {},
-	"gmbh":           {},
-	"gold":           {},
-	"golf":           {},
-	"graphics":       {},
-	"gratis":         {},
-	"green":          {},
-	"gripe":          {},
-	"group":          {},
-	"guide":          {},
-	"guitars":        {},
-	"guru":           {},
-	"haus":           {},
-	"healthcare":     {},
-	"help":           {},
-	"hiphop":         {},
-	"hockey":         {},
-	"holdings":       {},
-	"holiday":        {},
-	"horse":          {},
-	"hospital":       {},
-	"host":           {},
-	"hosting":        {},
-	"house":          {},
-	"how":            {},
-	"immo":           {},
-	"immobilien":     {},
-	"industries":     {},
-	"ink":            {},
-	"institute":      {},
-	"insure":         {},
-	"international":  {},
-	"investments":    {},
-	"irish":          {},
-	"jewelry":        {},
-	"jobs":           {},
-	"juegos":         {},
-	"kaufen":         {},
-	"kim":            {},
-	"kitchen":        {},
-	"kiwi":           {},
-	"land":           {},
-	"lawyer":         {},
-	"lease":          {},
-	"legal":          {},
-	"life":           {},
-	"lighting":       {},
-	"limited":        {},
-	"limo":           {},
-	"link":           {},
-	"live":           {},
-	"llc":            {},
-	"loan":           {},
-	"loans":          {},
-	"lol":            {},
-	"london":         {},
-	"love":           {},
-	"ltd":            {},
-	"luxe":           {},
-	"luxury":         {},
-	"maison":         {},
-	"management":     {},
-	"market":         {},
-	"marketing":      {},
-	"mba":            {},
-	"media":          {},
-	"memorial":       {},
-	"men":            {},
-	"menu":           {},
-	"miami":          {},
-	"moda":           {},
-	"moe":            {},
-	"mom":            {},
-	"money":          {},
-	"mortgage":       {},
-	"moscow":         {},
-	"movie":          {},
-	"navy":           {},
-	"network":        {},
-	"news":           {},
-	"ninja":          {},
-	"observer":       {},
-	"one":            {},
-	"onl":            {},
-	"online":         {},
-	"ooo":            {},
-	"page":           {},
-	"paris":          {},
-	"partners":       {},
-	"parts":          {},
-	"party":          {},
-	"pet":            {},
-	"photo":          {},
-	"photography":    {},
-	"photos":         {},
-	"pics":           {},
-	"pictures":       {},
-	"pink":           {},
-	"pizza":          {},
-	"plumbing":       {},
-	"plus":           {},
-	"poker":          {},
-	"press":          {},
-	"productions":    {},
-	"promo":          {},
-	"properties":     {},
-	"property":       {},
-	"protection":     {},
-	"pub":            {},
-	"qpon":           {},
-	"racing":         {},
-	"radio":          {},
-	"radio.am":       {},
-	"radio.fm":       {},
-	"realty":         {},
-	"recipes":        {},
-	"red":            {},
-	"rehab":          {},
-	"reisen":         {},
-	"rent":           {},
-	"rentals":        {},
-	"repair":         {},
-	"report":         {},
-	"republican":     {},
-	"rest":           {},
-	"restaurant":     {},
-	"review":         {},
-	"reviews":        {},
-	"rich":           {},
-	"rip":            {},
-	"rocks":          {},
-	"rodeo":          {},
-	"run":            {},
-	"sale":           {},
-	"salon":          {},
-	"sarl":           {},
-	"school":         {},
-	"schule":         {},
-	"science":        {},
-	"security":       {},
-	"services":       {},
-	"sex":            {},
-	"sexy":           {},
-	"shiksha":        {},
-	"shoes":          {},
-	"shop":           {},
-	"shopping":       {},
-	"show":           {},
-	"singles":        {},
-	"site":           {},
-	"ski":            {},
-	"soccer":         {},
-	"social":         {},
-	"software":       {},
-	"solar":          {},
-	"solutions":      {},
-	"soy":            {},
-	"space":          {},
-	"sport":          {},
-	"store":          {},
-	"stream":         {},
-	"studio":         {},
-	"study":          {},
-	"style":          {},
-	"sucks":          {},
-	"supplies":       {},
-	"supply":         {},
-	"support":        {},
-	"surf":           {},
-	"surgery":        {},
-	"systems":        {},
-	"tatar":          {},
-	"tattoo":         {},
-	"tax":            {},
-	"taxi":           {},
-	"team":           {},
-	"tech":           {},
-	"technology":     {},
-	"tennis":         {},
-	"theater":        {},
-	"theatre":        {},
-	"tickets":        {},
-	"tienda":         {},
-	"tips":           {},
-	"tires":          {},
-	"tirol":          {},
-	"today":          {},
-	"tools":          {},
-	"top":            {},
-	"tours":          {},
-	"town":           {},
-	"toys":           {},
-	"trade":          {},
-	"trading":        {},
-	"training":       {},
-	"tube":           {},
-	"tv":             {},
-	"university":     {},
-	"uno":            {},
-	"vacations":      {},
-	"vegas":          {},
-	"ventures":       {},
-	"vet":            {},
-	"viajes":         {},
-	"video":          {},
-	"villas":         {},
-	"vin":            {},
-	"vip":            {},
-	"vision":         {},
-	"vodka":          {},
-	"vote":           {},
-	"voting":         {},
-	"voto":           {},
-	"voyage":         {},
-	"watch":          {},
-	"webcam":         {},
-	"website":        {},
-	"wedding":        {},
-	"wien":           {},
-	"wiki":           {},
-	"win":            {},
-	"wine":           {},
-	"work":           {},
-	"works":          {},
-	"world":          {},
-	"wtf":            {},
-	"xyz":            {},
-	"yoga":           {},
-	"zone":           {},
-	"дети":           {},
-	"москва":         {},
-	"онлайн":         {},
-	"орг":            {},
-	"рус":            {},
-	"сайт":           {},
-}
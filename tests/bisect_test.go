@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"testing"
+	"time"
+)
+
+func Test_Strategy_Bisect(t *testing.T) {
+	s, _ := benchmark.GetStrategy(benchmark.StrategyBisect)
+	steps := []benchmark.Step{
+		{Attempts: 16},
+		{Attempts: 32},
+		{Attempts: 64, Errors: 10},
+	}
+
+	state, count, attempts := s.NextStep(&benchmark.StepHistory{Steps: steps, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlInProgress, state)
+	assert.Equal(t, 32, count)
+	assert.Equal(t, 48, attempts)
+
+	steps = append(steps, benchmark.Step{Attempts: 48}, benchmark.Step{Attempts: 56, Errors: 1})
+	state, count, attempts = s.NextStep(&benchmark.StepHistory{Steps: steps, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlInProgress, state)
+	assert.Equal(t, 48, count)
+	assert.Equal(t, 52, attempts)
+
+	steps = append(steps, benchmark.Step{Attempts: 52, Errors: 1})
+	state, count, _ = s.NextStep(&benchmark.StepHistory{Steps: steps, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlReady, state)
+	assert.Equal(t, 48, count)
+
+	state, count, _ = s.NextStep(&benchmark.StepHistory{
+		Steps:  []benchmark.Step{{Attempts: 16, Errors: 16}, {Attempts: 8, Errors: 8}, {Attempts: 4, Errors: 4}},
+		Limits: strategyLimits,
+	})
+	assert.Equal(t, benchmark.StateUrlFailed, state)
+	assert.Equal(t, 0, count)
+}
+
+func Test_Bisect_ToleranceValidated(t *testing.T) {
+	options := benchmark.OverloadOptions{
+		WorkersCount:         1,
+		InitConnectionsCount: 16,
+		MaxLimit:             768,
+		MaxConnections:       912,
+		Method:               benchmark.StrategyBisect,
+		ResponseTimeout:      5 * time.Second,
+		BisectTolerance:      4,
+		StepRounds:           1,
+		Mode:                 benchmark.ModeBurst,
+	}
+	assert.NoError(t, options.Validate())
+
+	options.BisectTolerance = 0
+	assert.Error(t, options.Validate())
+	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	err := overload.StartBackground(options)
+	if err != benchmark.ErrAlreadyStarted {
+		assert.EqualError(t, err, "invalid overload bisect tolerance: 0")
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/cache"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type counterCodec struct {
+	version int
+}
+
+func (c counterCodec) Kind() string {
+	return "counter"
+}
+
+func (c counterCodec) Version() int {
+	return c.version
+}
+
+func (c counterCodec) Encode(value interface{}) ([]byte, error) {
+	n, ok := value.(int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value %T", value)
+	}
+	return []byte(strconv.Itoa(n)), nil
+}
+
+func (c counterCodec) Decode(data []byte) (interface{}, error) {
+	return strconv.Atoi(string(data))
+}
+
+type otherCodec struct {
+	counterCodec
+}
+
+func (c otherCodec) Kind() string {
+	return "other"
+}
+
+func Test_CacheCodec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fileName := filepath.Join(dir, "cache.log")
+
+	storage, err := cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c := cache.New(storage)
+	v1 := counterCodec{version: 1}
+	assert.NoError(t, c.SetEntry("counter", v1, 42, time.Minute))
+	assert.Error(t, c.SetEntry("invalid", v1, "42", time.Minute))
+	c.Set("plain", 42, time.Minute)
+
+	v, err := c.GetEntry("counter", v1)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	_, err = c.GetEntry("plain", v1)
+	assert.Equal(t, cache.ErrUnexpectedKind, err)
+	_, err = c.GetEntry("counter", otherCodec{v1})
+	assert.Equal(t, cache.ErrUnexpectedKind, err)
+	_, err = c.GetEntry("invalid", v1)
+	assert.Equal(t, cache.ErrNotExists, err)
+	assert.NoError(t, c.Close())
+
+	// entries survive restarts, old schema versions are rejected
+	storage, err = cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c = cache.New(storage)
+	defer func() {
+		_ = c.Close()
+	}()
+	v, err = c.GetEntry("counter", v1)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	_, err = c.GetEntry("counter", counterCodec{version: 2})
+	assert.Equal(t, cache.ErrSchemaVersion, err)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/cache"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CacheLimits_Items(t *testing.T) {
+	c := cache.New(cache.NewMemoryStorage())
+	assert.NoError(t, c.SetLimits(cache.Limits{MaxItems: 3}))
+	c.Set("a", "a", time.Minute).
+		Set("b", "b", time.Minute).
+		Set("c", "c", time.Minute)
+	_, err := c.Get("a")
+	assert.NoError(t, err)
+	c.Set("d", "d", time.Minute)
+
+	_, err = c.Get("b")
+	assert.Equal(t, cache.ErrNotExists, err)
+	for _, name := range []string{"a", "c", "d"} {
+		_, err = c.Get(name)
+		assert.NoError(t, err, name)
+	}
+	stats := c.Stats()
+	assert.Equal(t, 3, stats.Items)
+	assert.Equal(t, 3, stats.MaxItems)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	// lowered limits evict at once
+	assert.NoError(t, c.SetLimits(cache.Limits{MaxItems: 1}))
+	assert.Equal(t, 1, c.Stats().Items)
+	assert.Equal(t, uint64(3), c.Stats().Evictions)
+}
+
+func Test_CacheLimits_Bytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fileName := filepath.Join(dir, "cache.log")
+
+	storage, err := cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c := cache.New(storage)
+	assert.NoError(t, c.SetLimits(cache.Limits{MaxBytes: 4096}))
+	value := strings.Repeat("x", 1500)
+	c.Set("a", value, time.Minute).
+		Set("b", value, time.Minute).
+		Set("c", value, time.Minute)
+
+	_, err = c.Get("a")
+	assert.Equal(t, cache.ErrNotExists, err)
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Items)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.True(t, stats.Bytes <= 4096)
+	assert.True(t, stats.EvictedBytes > 1500)
+	assert.NoError(t, c.Close())
+
+	// evicted items are forgotten by the log too
+	storage, err = cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c = cache.New(storage)
+	defer func() {
+		_ = c.Close()
+	}()
+	_, err = c.Get("a")
+	assert.Equal(t, cache.ErrNotExists, err)
+	v, err := c.Get("c")
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+}
+
+func Test_CacheLimits_Pinned(t *testing.T) {
+	c := cache.New(cache.NewMemoryStorage())
+	assert.NoError(t, c.SetLimits(cache.Limits{MaxItems: 2}))
+	c.SetPinned("pinned", "a", time.Minute)
+	assert.True(t, c.SetIfNotExists("marker", "b", time.Minute))
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("item-%d", i), "c", time.Minute)
+	}
+	assert.True(t, c.Exists("pinned"))
+	assert.True(t, c.Exists("marker"))
+	assert.Equal(t, 3, c.Stats().Items)
+	assert.Equal(t, uint64(9), c.Stats().Evictions)
+
+	// setting the name again unpins it
+	c.Set("pinned", "a", time.Minute)
+	c.Set("item-10", "c", time.Minute)
+	assert.False(t, c.Exists("pinned"))
+	assert.True(t, c.Exists("marker"))
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/cache"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CacheStorage_Redis(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer server.Close()
+	server.RequireAuth("secret")
+
+	_, err = cache.NewRedisStorage(server.Addr(), "wrong", 0, "test:")
+	assert.Error(t, err)
+
+	// two replicas sharing the server
+	storageA, err := cache.NewRedisStorage(server.Addr(), "secret", 0, "test:")
+	assert.NoError(t, err)
+	storageB, err := cache.NewRedisStorage(server.Addr(), "secret", 0, "test:")
+	assert.NoError(t, err)
+	a, b := cache.New(storageA), cache.New(storageB)
+	defer func() {
+		_ = a.Close()
+		_ = b.Close()
+	}()
+
+	a.Set("kept", "value", time.Minute).
+		Set("slice", []string{"a", "b"}, time.Minute).
+		Set("deleted", "value", time.Minute).
+		Set("expired", "value", time.Second).
+		Set("memory", &notRegistered{Value: "value"}, time.Minute)
+	assert.NoError(t, a.Delete("deleted"))
+	server.FastForward(2 * time.Second)
+
+	v, err := b.Get("kept")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", v)
+	v, err = b.Get("slice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, v)
+	for _, name := range []string{"deleted", "expired", "memory"} {
+		_, err = b.Get(name)
+		assert.Equal(t, cache.ErrNotExists, err, name)
+	}
+	v, err = a.Get("memory")
+	assert.NoError(t, err)
+	assert.Equal(t, &notRegistered{Value: "value"}, v)
+	assert.True(t, server.Exists("test:kept"))
+	assert.Equal(t, 2, storageB.Len())
+	assert.Equal(t, 3, storageA.Len())
+
+	// only one replica gets the marker
+	assert.True(t, a.SetIfNotExists("lease", "a", time.Second))
+	assert.False(t, b.SetIfNotExists("lease", "b", time.Second))
+	assert.False(t, a.SetIfNotExists("lease", "a", time.Second))
+	v, err = b.Get("lease")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v)
+	server.FastForward(2 * time.Second)
+	assert.True(t, b.SetIfNotExists("lease", "b", time.Second))
+
+	// the connection is restored after the server restart
+	server.Close()
+	assert.NoError(t, server.Restart())
+	b.Set("restarted", "value", time.Minute)
+	v, err = a.Get("restarted")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+func Test_CacheStorage_RedisGC(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer server.Close()
+
+	storage, err := cache.NewRedisStorage(server.Addr(), "", 0, "gc:")
+	assert.NoError(t, err)
+	c := cache.New(storage)
+	defer func() {
+		_ = c.Close()
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, c.StartBackground(ctx, 50*time.Millisecond, false))
+
+	c.Set("shared", "value", time.Minute).
+		Set("memory", &notRegistered{Value: "value"}, 100*time.Millisecond)
+	assert.Equal(t, 1, storage.(cache.BoundedStorage).Stats().Items)
+	time.Sleep(250 * time.Millisecond)
+
+	// the expired memory value is collected, the shared one is left to the server
+	assert.Equal(t, 0, storage.(cache.BoundedStorage).Stats().Items)
+	_, err = c.Get("memory")
+	assert.Equal(t, cache.ErrNotExists, err)
+	v, err := c.Get("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+// slowRedis answers PING and GET of missing keys, GET of the "slow" key
+// is answered after 200ms.
+func slowRedis(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+				rd := bufio.NewReader(conn)
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						_, _ = rd.ReadString('\n')
+						arg, _ := rd.ReadString('\n')
+						args[i] = strings.TrimSpace(arg)
+					}
+					switch {
+					case args[0] == "PING":
+						_, _ = conn.Write([]byte("+PONG\r\n"))
+					case args[0] == "GET" && args[1] == "test:slow":
+						time.Sleep(200 * time.Millisecond)
+						_, _ = conn.Write([]byte("$-1\r\n"))
+					default:
+						_, _ = conn.Write([]byte("$-1\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func Test_CacheStorage_RedisConcurrent(t *testing.T) {
+	ln := slowRedis(t)
+	defer func() {
+		_ = ln.Close()
+	}()
+	storage, err := cache.NewRedisStorage(ln.Addr().String(), "", 0, "test:")
+	assert.NoError(t, err)
+	c := cache.New(storage)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	slow := make(chan error)
+	go func() {
+		_, err := c.Get("slow")
+		slow <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// a slow round trip blocks neither the cache nor the other round trips
+	start := time.Now()
+	_, err = c.Get("fast")
+	assert.Equal(t, cache.ErrNotExists, err)
+	assert.False(t, c.Exists("fast"))
+	assert.True(t, time.Since(start) < 100*time.Millisecond, time.Since(start))
+	assert.Equal(t, cache.ErrNotExists, <-slow)
+}
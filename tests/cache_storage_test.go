@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/cache"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type notRegistered struct {
+	Value string
+}
+
+func Test_CacheStorage_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fileName := filepath.Join(dir, "data", "cache.log")
+
+	storage, err := cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c := cache.New(storage)
+	c.Set("kept", "value", time.Minute).
+		Set("replaced", "old", time.Minute).
+		Set("replaced", "new", time.Minute).
+		Set("deleted", "value", time.Minute).
+		Set("expired", "value", time.Millisecond).
+		Set("memory", &notRegistered{Value: "value"}, time.Minute).
+		Set("slice", []string{"a", "b"}, time.Minute)
+	assert.NoError(t, c.Delete("deleted"))
+	v, err := c.Get("memory")
+	assert.NoError(t, err)
+	assert.Equal(t, &notRegistered{Value: "value"}, v)
+	assert.NoError(t, c.Close())
+	time.Sleep(10 * time.Millisecond)
+
+	// a torn write at the tail is dropped
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, _ = f.Write([]byte{0, 0, 1, 0, 1, 2})
+	_ = f.Close()
+
+	storage, err = cache.NewFileStorage(fileName)
+	assert.NoError(t, err)
+	c = cache.New(storage)
+	defer func() {
+		_ = c.Close()
+	}()
+	v, err = c.Get("kept")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", v)
+	v, err = c.Get("replaced")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", v)
+	v, err = c.Get("slice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, v)
+	for _, name := range []string{"deleted", "expired", "memory"} {
+		_, err = c.Get(name)
+		assert.Equal(t, cache.ErrNotExists, err, name)
+	}
+	assert.Equal(t, 3, storage.Len())
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Domain_RootDomain(t *testing.T) {
+	for host, expected := range map[string]string{
+		"example.com":               "example.com",
+		"WWW.Example.COM.":          "example.com",
+		"news.example.co.uk":        "example.co.uk",
+		"a.b.example.io":            "example.io",
+		"shop.example.com.ru":       "example.com.ru",
+		"foo.github.io":             "foo.github.io",
+		"a.b.foo.ck":                "b.foo.ck",
+		"www.ck":                    "www.ck",
+		"a.www.ck":                  "www.ck",
+		"com":                       "com",
+		"localhost":                 "localhost",
+		"unknown.tld-not-in-list":   "unknown.tld-not-in-list",
+		"a.unknown.tld-not-in-list": "unknown.tld-not-in-list",
+		"127.0.0.1":                 "127.0.0.1",
+		"www.пример.рф":             "xn--e1afmkfd.xn--p1ai",
+		"www.xn--e1afmkfd.xn--p1ai": "xn--e1afmkfd.xn--p1ai",
+		"Пример.РФ":                 "xn--e1afmkfd.xn--p1ai",
+	} {
+		assert.Equal(t, expected, domain.GetRootDomain(host), host)
+	}
+}
+
+func Test_Domain_ParseList(t *testing.T) {
+	l, err := domain.ParseList(strings.NewReader("// comment\n\ncom\n*.kawasaki.jp\n!city.kawasaki.jp\n"))
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+
+	_, err = domain.ParseList(strings.NewReader("// only comments\n"))
+	assert.Error(t, err)
+}
+
+func Test_Domain_LoadList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "psl")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fileName := filepath.Join(dir, "public_suffix_list.dat")
+
+	assert.Error(t, domain.LoadList(fileName))
+	assert.Equal(t, "example.co.uk", domain.GetRootDomain("news.example.co.uk"))
+
+	// the embedded snapshot with an extra suffix
+	snapshot, err := ioutil.ReadFile("../src/domain/public_suffix_list.dat")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(fileName, append(snapshot, []byte("\nbenchmark.test\n")...), 0644))
+	assert.NoError(t, domain.LoadList(fileName))
+	assert.Equal(t, "b.benchmark.test", domain.GetRootDomain("a.b.benchmark.test"))
+	assert.Equal(t, "example.co.uk", domain.GetRootDomain("news.example.co.uk"))
+}
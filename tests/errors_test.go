@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_ClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected string
+	}{
+		{200, ""},
+		{204, ""},
+		{301, benchmark.ErrorClassRedirect},
+		{302, benchmark.ErrorClassRedirect},
+		{304, benchmark.ErrorClassRedirect},
+		{403, benchmark.ErrorClassHttp4xx},
+		{404, benchmark.ErrorClassHttp4xx},
+		{429, benchmark.ErrorClassRateLimited},
+		{500, benchmark.ErrorClassHttp5xx},
+		{503, benchmark.ErrorClassHttp5xx},
+		{101, benchmark.ErrorClassHttpOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, benchmark.ClassifyStatus(tt.status), tt.status)
+	}
+}
+
+func Test_ClassifyTransportError(t *testing.T) {
+	syscallErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"timeout", fasthttp.ErrTimeout, benchmark.ErrorClassTimeout},
+		{"dial timeout", fasthttp.ErrDialTimeout, benchmark.ErrorClassTimeout},
+		{"wrapped timeout", fmt.Errorf("load: %w", fasthttp.ErrTimeout), benchmark.ErrorClassTimeout},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid"}, benchmark.ErrorClassDns},
+		{"refused", syscallErr(syscall.ECONNREFUSED), benchmark.ErrorClassConnRefused},
+		{"reset", syscallErr(syscall.ECONNRESET), benchmark.ErrorClassConnReset},
+		{"closed", fasthttp.ErrConnectionClosed, benchmark.ErrorClassConnReset},
+		{"unknown authority", x509.UnknownAuthorityError{}, benchmark.ErrorClassTls},
+		{"tls alert", errors.New("tls: handshake failure"), benchmark.ErrorClassTls},
+		{"redirects", fasthttp.ErrTooManyRedirects, benchmark.ErrorClassRedirect},
+		{"no location", fasthttp.ErrMissingLocation, benchmark.ErrorClassRedirect},
+		{"other", errors.New("something went wrong"), benchmark.ErrorClassOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, benchmark.ClassifyTransportError(tt.err), tt.name)
+	}
+}
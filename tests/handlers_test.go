@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/handlers"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Handler_Benchmark_Rounds(t *testing.T) {
+	var requests int32
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the first round of the second step fails once
+		if atomic.AddInt32(&requests, 1) == 5 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// 2 connections, then 4 connections recommended whatever the errors are
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		switch len(h.Steps) {
+		case 0:
+			return benchmark.StateUrlInProgress, 0, 2
+		case 1:
+			return benchmark.StateUrlInProgress, 2, 4
+		}
+		return benchmark.StateUrlReady, 4, 0
+	})
+
+	rec := httptest.NewRecorder()
+	handlers.Benchmark(rec, httptest.NewRequest(http.MethodPost, "/benchmark", strings.NewReader(server.URL+"/")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	line := rec.Body.String()
+	assert.True(t, strings.HasPrefix(line, "  4: "+host+" (measured; confidence 75%; "), line)
+	assert.True(t, strings.HasSuffix(line, " req/s; errors http_5xx: 1)\n"), line)
+	assert.Equal(t, int32(2*testStepRounds+4*testStepRounds), atomic.LoadInt32(&requests))
+
+	// the cached result
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/benchmark?format=json", strings.NewReader(server.URL+"/"))
+	handlers.Benchmark(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var res map[string]struct {
+		Recommended int                `json:"recommended"`
+		Status      string             `json:"status"`
+		Partial     bool               `json:"partial"`
+		Urls        []string           `json:"urls"`
+		Confidence  float64            `json:"confidence"`
+		Throughput  float64            `json:"throughput"`
+		Errors      map[string]int     `json:"errors"`
+		Latency     map[string]float64 `json:"latency"`
+		Timeline    [][]struct {
+			Requests int `json:"requests"`
+			Errors   int `json:"errors"`
+		} `json:"timeline"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	if assert.Contains(t, res, host) {
+		hr := res[host]
+		assert.Equal(t, 4, hr.Recommended)
+		assert.Equal(t, "ready", hr.Status)
+		assert.False(t, hr.Partial)
+		assert.Equal(t, []string{server.URL + "/"}, hr.Urls)
+		assert.Equal(t, 0.75, hr.Confidence)
+		assert.True(t, hr.Throughput > 0)
+		assert.Equal(t, map[string]int{benchmark.ErrorClassHttp5xx: 1}, hr.Errors)
+		assert.True(t, hr.Latency["max"] >= hr.Latency["min"])
+		// the recommended step has a timeline per round
+		if assert.Len(t, hr.Timeline, testStepRounds) {
+			for round, timeline := range hr.Timeline {
+				requests, errs := 0, 0
+				for _, interval := range timeline {
+					requests += interval.Requests
+					errs += interval.Errors
+				}
+				expectedErrs := 0
+				if round == 0 {
+					expectedErrs = 1
+				}
+				assert.Equal(t, 4, requests)
+				assert.Equal(t, expectedErrs, errs)
+			}
+		}
+	}
+	assert.Equal(t, int32(2*testStepRounds+4*testStepRounds), atomic.LoadInt32(&requests))
+}
+
+func Test_Handler_Benchmark_InvalidList(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlers.Benchmark(rec, httptest.NewRequest(http.MethodPost, "/benchmark", strings.NewReader("ftp://example.org/")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// the cloud metadata address is not loaded
+	dataProvider.AllowPrivateHosts(false)
+	rec = httptest.NewRecorder()
+	handlers.Benchmark(rec, httptest.NewRequest(http.MethodPost, "/benchmark", strings.NewReader("http://169.254.169.254/latest/meta-data/")))
+	dataProvider.AllowPrivateHosts(true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handlers.Benchmark(rec, httptest.NewRequest(http.MethodGet, "/benchmark", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/jobs"
+	"lubyshev/go-site-benchmark/src/yandex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newJobHost serves the host with the delay and lets it be ready after one step.
+func newJobHost(t *testing.T, delay time.Duration) (*httptest.Server, string) {
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(delay)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		if len(h.Steps) == 0 {
+			return benchmark.StateUrlInProgress, 0, 1
+		}
+		return benchmark.StateUrlReady, 1, 0
+	})
+	return server, host
+}
+
+func waitJob(t *testing.T, id string) *jobs.Job {
+	for i := 0; i < 100; i++ {
+		job, err := jobs.GetManager().Get(id)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		if job.State != jobs.StatePending && job.State != jobs.StateRunning {
+			return job
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("job %s is not finished", id)
+	return nil
+}
+
+func Test_Jobs_Create(t *testing.T) {
+	jobs.GetManager().Configure(100, 10*time.Second, time.Minute)
+	server, host := newJobHost(t, 0)
+	defer server.Close()
+
+	job, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL, server.URL + "/a"})
+	assert.NoError(t, err)
+	job = waitJob(t, job.Id)
+	assert.Equal(t, jobs.StateDone, job.State)
+	assert.Equal(t, map[string][]string{host: {server.URL + "/", server.URL + "/a"}}, job.Sites.Items)
+	if assert.Contains(t, job.Results, host) {
+		assert.Equal(t, benchmark.StateUrlReady, job.Results[host].State)
+		assert.Equal(t, 1, job.Results[host].Count)
+	}
+	assert.Equal(t, jobs.ErrAlreadyFinish, jobs.GetManager().Cancel(job.Id))
+}
+
+func Test_Jobs_Invalid(t *testing.T) {
+	jobs.GetManager().Configure(100, 10*time.Second, time.Minute)
+	_, err := jobs.GetManager().Create(" ", "", yandex.SearchParams{}, nil)
+	assert.Equal(t, jobs.ErrEmptyJob, err)
+	_, err = jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{" "})
+	assert.Equal(t, jobs.ErrEmptyJob, err)
+	for _, hosts := range [][]string{{"https://https://"}, {"example.com", "ftp://example.org/"}, {"localhost"}} {
+		_, err = jobs.GetManager().Create("", "", yandex.SearchParams{}, hosts)
+		assert.True(t, errors.Is(err, jobs.ErrInvalidHosts), hosts)
+	}
+	_, err = jobs.GetManager().Create("foobar", "blabla", yandex.SearchParams{}, nil)
+	assert.Equal(t, jobs.ErrInvalidSearch, err)
+	_, err = jobs.GetManager().Get("blabla")
+	assert.Equal(t, jobs.ErrNotFound, err)
+	assert.Equal(t, jobs.ErrNotFound, jobs.GetManager().Cancel("blabla"))
+}
+
+func Test_Jobs_Cancel(t *testing.T) {
+	jobs.GetManager().Configure(100, 10*time.Second, time.Minute)
+	var requests int32
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	// 1, 2 and 4 connections, then ready
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		if l := len(h.Steps); l < 3 {
+			return benchmark.StateUrlInProgress, h.Attempts, 1 << l
+		}
+		return benchmark.StateUrlReady, 4, 0
+	})
+	measured := int32((1 + 2 + 4) * testStepRounds)
+
+	job, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL})
+	assert.NoError(t, err)
+	// in the middle of the second step
+	time.Sleep(900 * time.Millisecond)
+	assert.NoError(t, jobs.GetManager().Cancel(job.Id))
+	assert.Equal(t, jobs.ErrAlreadyFinish, jobs.GetManager().Cancel(job.Id))
+	job = waitJob(t, job.Id)
+	assert.Equal(t, jobs.StateCancelled, job.State)
+	// the partial result is kept
+	if assert.Contains(t, job.Results, host) {
+		assert.False(t, job.Results[host].Done())
+		assert.Equal(t, 1, job.Results[host].Count)
+	}
+
+	// the load is stopped after the round in flight
+	time.Sleep(400 * time.Millisecond)
+	stopped := atomic.LoadInt32(&requests)
+	time.Sleep(700 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&requests))
+	assert.True(t, stopped < measured, stopped)
+
+	// the host waited for by another job is measured to the end
+	atomic.StoreInt32(&requests, 0)
+	cancelled, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL})
+	assert.NoError(t, err)
+	other, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL})
+	assert.NoError(t, err)
+	time.Sleep(500 * time.Millisecond)
+	assert.NoError(t, jobs.GetManager().Cancel(cancelled.Id))
+	other = waitJob(t, other.Id)
+	assert.Equal(t, jobs.StateDone, other.State)
+	if assert.Contains(t, other.Results, host) {
+		assert.Equal(t, benchmark.StateUrlReady, other.Results[host].State)
+		assert.Equal(t, 4, other.Results[host].Count)
+	}
+	assert.Equal(t, measured, atomic.LoadInt32(&requests))
+}
+
+func Test_Jobs_Timeout(t *testing.T) {
+	jobs.GetManager().Configure(100, 200*time.Millisecond, time.Minute)
+	server, _ := newJobHost(t, time.Second)
+	defer server.Close()
+
+	job, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL})
+	assert.NoError(t, err)
+	job = waitJob(t, job.Id)
+	assert.Equal(t, jobs.StateFailed, job.State)
+	assert.Equal(t, "job timed out", job.Error)
+}
+
+func Test_Jobs_History(t *testing.T) {
+	jobs.GetManager().Configure(2, 10*time.Second, time.Minute)
+	server, _ := newJobHost(t, 0)
+	defer server.Close()
+
+	ids := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		job, err := jobs.GetManager().Create("", "", yandex.SearchParams{}, []string{server.URL})
+		assert.NoError(t, err)
+		waitJob(t, job.Id)
+		ids = append(ids, job.Id)
+	}
+
+	// the oldest finished jobs are forgotten on create
+	list := jobs.GetManager().List()
+	assert.Len(t, list, 2)
+	assert.Equal(t, ids[3], list[0].Id)
+	assert.Equal(t, ids[2], list[1].Id)
+	_, err := jobs.GetManager().Get(ids[0])
+	assert.Equal(t, jobs.ErrNotFound, err)
+}
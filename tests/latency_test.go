@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"testing"
+	"time"
+)
+
+func Test_LatencyStats(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		res := make([]time.Duration, 0, len(values))
+		for _, v := range values {
+			res = append(res, time.Duration(v)*time.Millisecond)
+		}
+		return res
+	}
+	hundred := make([]int, 0, 100)
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, i)
+	}
+
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		expected  benchmark.LatencyStats
+	}{
+		{"empty", nil, benchmark.LatencyStats{}},
+		{"one sample", ms(42), benchmark.LatencyStats{
+			Min: 42 * time.Millisecond, Avg: 42 * time.Millisecond, P50: 42 * time.Millisecond,
+			P95: 42 * time.Millisecond, P99: 42 * time.Millisecond, Max: 42 * time.Millisecond,
+		}},
+		{"unsorted", ms(30, 10, 20, 40), benchmark.LatencyStats{
+			Min: 10 * time.Millisecond, Avg: 25 * time.Millisecond, P50: 20 * time.Millisecond,
+			P95: 40 * time.Millisecond, P99: 40 * time.Millisecond, Max: 40 * time.Millisecond,
+		}},
+		{"hundred samples", ms(hundred...), benchmark.LatencyStats{
+			Min: 1 * time.Millisecond, Avg: 50500 * time.Microsecond, P50: 50 * time.Millisecond,
+			P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond,
+		}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, benchmark.NewLatencyStats(tt.latencies), tt.name)
+	}
+
+	// the input is not reordered
+	latencies := ms(30, 10, 20)
+	benchmark.NewLatencyStats(latencies)
+	assert.Equal(t, ms(30, 10, 20), latencies)
+}
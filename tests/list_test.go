@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"testing"
+)
+
+func Test_List_Lines(t *testing.T) {
+	sites, err := dataProvider.GetAdapter(dataProvider.DataProviderList).GetData(context.Background(), `
+https://news.example.com/a
+http://Example.com
+news.example.com/a
+
+shop.example.com.ru/b?x=1
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"example.com":    {"http://example.com/", "https://news.example.com/a"},
+		"example.com.ru": {"https://shop.example.com.ru/b?x=1"},
+	}, sites.Items)
+}
+
+func Test_List_Json(t *testing.T) {
+	sites, err := dataProvider.GetAdapter(dataProvider.DataProviderList).GetData(context.Background(),
+		`["https://a.example.org/", "example.org/b"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"example.org": {"https://a.example.org/", "https://example.org/b"},
+	}, sites.Items)
+}
+
+func Test_List_Invalid(t *testing.T) {
+	provider := dataProvider.GetAdapter(dataProvider.DataProviderList)
+	_, err := provider.GetData(context.Background(), "  \n ")
+	assert.Equal(t, dataProvider.ErrEmptyList, err)
+	_, err = provider.GetData(context.Background(), `["ftp://example.org/"]`)
+	assert.Error(t, err)
+	_, err = provider.GetData(context.Background(), `["https://example.org/"`)
+	assert.Error(t, err)
+	_, err = provider.GetData(context.Background(), "https://https://")
+	assert.Error(t, err)
+	_, err = provider.GetData(context.Background(), "https://exa_mple..com/")
+	assert.Error(t, err)
+}
+
+func Test_List_PrivateHosts(t *testing.T) {
+	dataProvider.AllowPrivateHosts(false)
+	defer dataProvider.AllowPrivateHosts(true)
+
+	for _, host := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "[::1]", "[fe80::1]", "[fd00::1]", "[::ffff:127.0.0.1]",
+	} {
+		_, err := dataProvider.ParseUrls([]string{"http://" + host + "/"})
+		assert.Error(t, err, host)
+	}
+	sites, err := dataProvider.ParseUrls([]string{"http://8.8.8.8/", "http://[2001:4860:4860::8888]/"})
+	assert.NoError(t, err)
+	assert.Len(t, sites.Items, 2)
+
+	dataProvider.AllowPrivateHosts(true)
+	_, err = dataProvider.ParseUrls([]string{"http://127.0.0.1:8080/"})
+	assert.NoError(t, err)
+}
@@ -2,12 +2,16 @@ package tests
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"lubyshev/go-site-benchmark/src/benchmark"
 	"lubyshev/go-site-benchmark/src/cache"
 	"lubyshev/go-site-benchmark/src/conf"
+	"lubyshev/go-site-benchmark/src/dataProvider"
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 var config *conf.TestConfig
@@ -20,12 +24,44 @@ func getConfig() *conf.TestConfig {
 	return config
 }
 
+// testOverloadOptions start the queue, Method is the scripted strategy
+var testOverloadOptions = benchmark.OverloadOptions{
+	WorkersCount:         4,
+	InitConnectionsCount: 1,
+	MaxLimit:             64,
+	MaxConnections:       256,
+	ResponseTimeout:      5 * time.Second,
+	BisectTolerance:      4,
+	StepRounds:           testStepRounds,
+	Mode:                 benchmark.ModeBurst,
+	StaleTtl:             time.Minute,
+}
+
 func TestMain(m *testing.M) {
 	ctxCache, ctxCacheCancelFunc := context.WithCancel(context.Background())
 	err := cache.GetCache().StartBackground(ctxCache, getConfig().CacheBgFrequency, false)
 	if err != nil {
 		log.Fatalf("ERROR: %s\n", err.Error())
 	}
-	defer ctxCacheCancelFunc()
-	os.Exit(m.Run())
+
+	// the test servers listen on loopback addresses
+	dataProvider.AllowPrivateHosts(true)
+
+	// the queue is process-wide: it is started once, every test measures
+	// hosts of its own scripted by setScript
+	strategy := fmt.Sprintf("test-script-%d", time.Now().UnixNano())
+	if err = benchmark.RegisterStrategy(strategy, benchmark.StrategyFunc(scriptedStep)); err != nil {
+		log.Fatalf("ERROR: %s\n", err.Error())
+	}
+	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	testOverloadOptions.Method = strategy
+	if err = overload.StartBackground(testOverloadOptions); err != nil {
+		log.Fatalf("ERROR: %s\n", err.Error())
+	}
+
+	code := m.Run()
+	overload.StopBackground()
+	_ = benchmark.UnregisterStrategy(strategy)
+	ctxCacheCancelFunc()
+	os.Exit(code)
 }
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/cache"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Overload_CacheLimits(t *testing.T) {
+	var requests int32
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		switch len(h.Steps) {
+		case 0:
+			return benchmark.StateUrlInProgress, 0, 1
+		case 1:
+			return benchmark.StateUrlInProgress, 1, 2
+		}
+		return benchmark.StateUrlReady, 2, 0
+	})
+
+	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	sites := &dataProvider.HostsToCheck{Items: map[string][]string{host: {server.URL + "/"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := overload.Benchmark(ctx, sites, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, benchmark.StateUrlQueued, res[host].State)
+
+	// the lease and the url in progress survive the cache filled past its limits
+	assert.NoError(t, cache.GetCache().SetLimits(cache.Limits{MaxItems: 10}))
+	defer func() {
+		_ = cache.GetCache().SetLimits(cache.Limits{})
+	}()
+	evictions := cache.GetCache().Stats().Evictions
+	for i := 0; i < 100; i++ {
+		cache.GetCache().Set(fmt.Sprintf("filler-%s-%d", host, i), "value", time.Minute)
+	}
+	assert.True(t, cache.GetCache().Stats().Evictions >= evictions+90)
+	res = overload.Progress(sites)
+	assert.False(t, res[host].Done(), res[host].State)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err = overload.Benchmark(ctx, sites, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, benchmark.StateUrlReady, res[host].State)
+	assert.Equal(t, 2, res[host].Count)
+	// measured once: the host has not been pushed again
+	assert.Equal(t, int32(testStepRounds+2*testStepRounds), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"fmt"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const testStepRounds = 2
+
+// scripts are the strategies of the test hosts
+var scripts sync.Map
+
+// scriptedStep runs the script of the host, hosts without
+// a script are ready at once.
+func scriptedStep(h *benchmark.StepHistory) (string, int, int) {
+	if script, ok := scripts.Load(h.Host); ok {
+		return script.(benchmark.Strategy).NextStep(h)
+	}
+	return benchmark.StateUrlReady, 1, 0
+}
+
+func setScript(host string, script benchmark.StrategyFunc) {
+	scripts.Store(host, script)
+}
+
+var lastLoopback int32
+
+// newHostServer serves the handler on a loopback address of its own,
+// so the host is new for the process-wide queue and cache even when
+// the tests are repeated.
+func newHostServer(t *testing.T, handler http.Handler) (*httptest.Server, string) {
+	n := atomic.AddInt32(&lastLoopback, 1)
+	host := fmt.Sprintf("127.0.%d.%d", n/250+1, n%250+1)
+	listener, err := net.Listen("tcp", host+":0")
+	if err != nil {
+		t.Fatalf("listen %s: %s", host, err.Error())
+	}
+	server := httptest.NewUnstartedServer(handler)
+	_ = server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+
+	return server, host
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_ParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"seconds with spaces", " 5 ", 5 * time.Second},
+		{"negative seconds", "-5", 0},
+		{"past date", "Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"garbage", "soon", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, benchmark.ParseRetryAfter([]byte(c.value)))
+		})
+	}
+
+	date := time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)
+	d := benchmark.ParseRetryAfter([]byte(date))
+	assert.True(t, d > time.Minute+58*time.Second && d <= 2*time.Minute, d)
+}
+
+func Test_DetectRateLimit(t *testing.T) {
+	markers := [][]byte{[]byte("showcaptcha"), []byte("cf-chl-")}
+	cases := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		limited    bool
+		expected   time.Duration
+	}{
+		{"ok", http.StatusOK, "", "<html>ok</html>", false, 0},
+		{"too many requests", http.StatusTooManyRequests, "", "", true, 0},
+		{"too many requests with retry after", http.StatusTooManyRequests, "30", "", true, 30 * time.Second},
+		{"unavailable with retry after", http.StatusServiceUnavailable, "10", "", true, 10 * time.Second},
+		{"unavailable", http.StatusServiceUnavailable, "", "down for maintenance", false, 0},
+		{"captcha on ok", http.StatusOK, "", "<form action=/ShowCaptcha>", true, 0},
+		{"challenge on forbidden", http.StatusForbidden, "", `<div id="cf-chl-widget">`, true, 0},
+		{"challenge on unavailable", http.StatusServiceUnavailable, "", `<script src="/cf-chl-bypass">`, true, 0},
+		{"forbidden", http.StatusForbidden, "", "forbidden", false, 0},
+		{"marker on not found", http.StatusNotFound, "", "showcaptcha", false, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &fasthttp.Response{}
+			resp.SetStatusCode(c.status)
+			if c.retryAfter != "" {
+				resp.Header.Set("Retry-After", c.retryAfter)
+			}
+			limited, retryAfter := benchmark.DetectRateLimit(resp, []byte(c.body), markers)
+			assert.Equal(t, c.limited, limited)
+			assert.Equal(t, c.expected, retryAfter)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/robots"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public$
+Crawl-delay: 3
+
+User-agent: Yandex
+User-agent: go-site-benchmark
+Disallow: /*.pdf$
+Disallow: /search?
+Allow: /search?q=
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func Test_Robots_Common(t *testing.T) {
+	rules := robots.Parse([]byte(robotsTxt))
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, rules.Sitemaps)
+	assert.True(t, rules.Allowed("OtherBot/2.0", "/"))
+	assert.False(t, rules.Allowed("OtherBot/2.0", "/private/a"))
+	assert.True(t, rules.Allowed("OtherBot/2.0", "/private/public"))
+	assert.False(t, rules.Allowed("OtherBot/2.0", "/private/public/a"))
+	assert.True(t, rules.Allowed("OtherBot/2.0", "/robots.txt"))
+	assert.Equal(t, 3*time.Second, rules.CrawlDelay("OtherBot/2.0"))
+}
+
+func Test_Robots_Agent(t *testing.T) {
+	rules := robots.Parse([]byte(robotsTxt))
+	agent := "Go-Site-Benchmark/1.0"
+	assert.True(t, rules.Allowed(agent, "/private/a"))
+	assert.False(t, rules.Allowed(agent, "/docs/a.pdf"))
+	assert.True(t, rules.Allowed(agent, "/docs/a.pdf?download=1"))
+	assert.False(t, rules.Allowed(agent, "/search?text=a"))
+	assert.True(t, rules.Allowed(agent, "/search?q=a"))
+	assert.Equal(t, 500*time.Millisecond, rules.CrawlDelay(agent))
+}
+
+func Test_Robots_Empty(t *testing.T) {
+	rules := robots.Parse([]byte(""))
+	assert.True(t, rules.Allowed("go-site-benchmark", "/any"))
+	assert.Equal(t, time.Duration(0), rules.CrawlDelay("go-site-benchmark"))
+}
+
+func Test_Robots_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(robotsTxt))
+	}))
+	defer server.Close()
+	body, err := robots.FetchBody(context.Background(), server.URL, "")
+	assert.NoError(t, err)
+	assert.Equal(t, robotsTxt, string(body))
+	rules, err := robots.Fetch(context.Background(), server.URL, "")
+	assert.NoError(t, err)
+	assert.False(t, rules.Allowed("bot", "/private"))
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	body, err = robots.FetchBody(context.Background(), missing.URL, "")
+	assert.NoError(t, err)
+	assert.Empty(t, body)
+	rules, err = robots.Fetch(context.Background(), missing.URL, "")
+	assert.NoError(t, err)
+	assert.True(t, rules.Allowed("bot", "/private"))
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	_, err = robots.FetchBody(context.Background(), broken.URL, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/bing"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/duckduckgo"
+	"lubyshev/go-site-benchmark/src/google"
+	"lubyshev/go-site-benchmark/src/serp"
+	"lubyshev/go-site-benchmark/src/yandex"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// go test ./... -update rewrites the golden files
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func Test_Serp_Google(t *testing.T) {
+	res, err := google.ParseGoogleResponse(readTestdata(t, "google.html"))
+	assert.NoError(t, err)
+	lines := make([]string, 0, len(res.Items))
+	for _, item := range res.Items {
+		lines = append(lines, fmt.Sprintf("%s %s", item.Host, item.Url))
+	}
+	assertGolden(t, "google.golden", lines)
+}
+
+func Test_Serp_Bing(t *testing.T) {
+	res, err := bing.ParseBingResponse(readTestdata(t, "bing.html"))
+	assert.NoError(t, err)
+	lines := make([]string, 0, len(res.Items))
+	for _, item := range res.Items {
+		lines = append(lines, fmt.Sprintf("%s %s", item.Host, item.Url))
+	}
+	assertGolden(t, "bing.golden", lines)
+}
+
+func Test_Serp_DuckDuckGo(t *testing.T) {
+	res, err := duckduckgo.ParseDuckDuckGoResponse(readTestdata(t, "duckduckgo.html"))
+	assert.NoError(t, err)
+	lines := make([]string, 0, len(res.Items))
+	for _, item := range res.Items {
+		lines = append(lines, fmt.Sprintf("%s %s", item.Host, item.Url))
+	}
+	assertGolden(t, "duckduckgo.golden", lines)
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("can't read testdata: %s", err.Error())
+	}
+	return data
+}
+
+func assertGolden(t *testing.T, name string, lines []string) {
+	actual := strings.Join(lines, "\n") + "\n"
+	if *updateGolden {
+		if err := ioutil.WriteFile(filepath.Join("testdata", name), []byte(actual), 0644); err != nil {
+			t.Fatalf("can't update golden file: %s", err.Error())
+		}
+	}
+	assert.Equal(t, string(readTestdata(t, name)), actual)
+}
+
+func Test_Serp_SearchAdapter(t *testing.T) {
+	params := dataProvider.YandexParams()
+	for _, engine := range []string{"", "bing", "merge", "google, duckduckgo"} {
+		adapter, err := dataProvider.GetSearchAdapter(engine, params)
+		assert.NoError(t, err, engine)
+		assert.NotNil(t, adapter, engine)
+	}
+	for _, engine := range []string{"altavista", "merge,bing", "list", "yandex,sitemap"} {
+		_, err := dataProvider.GetSearchAdapter(engine, params)
+		assert.Error(t, err, engine)
+	}
+}
+
+func Test_Serp_YandexParams(t *testing.T) {
+	params := yandex.SearchParams{Region: 2, Pages: 3, NumDoc: 20, Device: yandex.DeviceDesktop}
+	assert.NoError(t, params.Validate())
+	adapter, err := dataProvider.GetSearchAdapter("yandex", params)
+	assert.NoError(t, err)
+	assert.NotNil(t, adapter)
+
+	for _, invalid := range []yandex.SearchParams{
+		{Region: 0, Pages: 1, NumDoc: 50, Device: yandex.DeviceTouch},
+		{Region: 213, Pages: 0, NumDoc: 50, Device: yandex.DeviceTouch},
+		{Region: 213, Pages: 11, NumDoc: 50, Device: yandex.DeviceTouch},
+		{Region: 213, Pages: 1, NumDoc: 51, Device: yandex.DeviceTouch},
+		{Region: 213, Pages: 1, NumDoc: 50, Device: "tv"},
+	} {
+		assert.Error(t, invalid.Validate(), invalid.String())
+		_, err = dataProvider.GetSearchAdapter("bing", invalid)
+		assert.Error(t, err, invalid.String())
+	}
+}
+
+func Test_Serp_BingClickUrl(t *testing.T) {
+	target := base64.RawURLEncoding.EncodeToString([]byte("https://example.com/page"))
+	res, err := bing.ParseBingResponse([]byte(`<ol id="b_results">
+<li class="b_algo"><h2><a href="https://www.bing.com/ck/a?u=a1` + target + `">1</a></h2></li>
+<li class="b_algo"><h2><a href="https://notbing.com/ck/a?u=a1` + target + `">2</a></h2></li>
+<li class="b_algo"><h2><a href="https://bing.com/search?q=foobar">3</a></h2></li>
+</ol>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []serp.ResponseItem{
+		{Host: "example.com", Url: "https://example.com/page"},
+		{Host: "notbing.com", Url: "https://notbing.com/ck/a?u=a1" + target},
+	}, res.Items)
+}
+
+func Test_Serp_CheckSearchResponse(t *testing.T) {
+	engine := &serp.Engine{Name: "test", CaptchaPaths: []string{"/sorry/"}, Markers: [][]byte{[]byte("g-recaptcha")}}
+	page, _ := url.Parse("https://search.test/search?q=foobar")
+	sorry, _ := url.Parse("https://search.test/sorry/index?continue=foobar")
+	cases := []struct {
+		name     string
+		status   int
+		url      *url.URL
+		body     string
+		expected error
+	}{
+		{"ok", http.StatusOK, page, `<a href="https://example.com/"><h3>example</h3></a>`, nil},
+		{"redirected to captcha", http.StatusOK, sorry, "", serp.ErrSearchBlocked},
+		{"captcha page", http.StatusOK, page, `<div class="G-reCAPTCHA"></div>`, serp.ErrSearchBlocked},
+		{"too many requests", http.StatusTooManyRequests, page, "", serp.ErrSearchBlocked},
+		{"service unavailable", http.StatusServiceUnavailable, page, "", serp.ErrSearchBlocked},
+		{"server error", http.StatusInternalServerError, page, "", serp.ErrSearchHTTPStatus},
+		{"forbidden", http.StatusForbidden, page, "", serp.ErrSearchHTTPStatus},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: c.status, Request: &http.Request{URL: c.url}}
+			err := engine.CheckSearchResponse(resp, []byte(c.body))
+			if c.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, c.expected), err)
+		})
+	}
+}
+
+// engineTransport answers the requests to every search engine host by its response.
+type engineTransport map[string]func() *http.Response
+
+func (t engineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := t[req.URL.Hostname()]()
+	resp.Request = req
+	return resp, nil
+}
+
+func Test_Serp_EnginesFailed(t *testing.T) {
+	page := func(status int, body string) func() *http.Response {
+		return func() *http.Response {
+			return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body))}
+		}
+	}
+	defaultClient := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: engineTransport{
+		"www.google.com":      page(http.StatusOK, `<form><div class="g-recaptcha"></div></form>`),
+		"www.bing.com":        page(http.StatusTooManyRequests, ""),
+		"html.duckduckgo.com": page(http.StatusBadGateway, ""),
+	}}
+	defer func() {
+		http.DefaultClient = defaultClient
+	}()
+
+	query := fmt.Sprintf("foobar %d", time.Now().UnixNano())
+	for engine, expected := range map[string]error{
+		"google":     serp.ErrSearchBlocked,
+		"bing":       serp.ErrSearchBlocked,
+		"duckduckgo": serp.ErrSearchHTTPStatus,
+	} {
+		adapter, _ := dataProvider.GetSearchAdapter(engine, dataProvider.YandexParams())
+		_, err := adapter.GetData(context.Background(), query)
+		assert.True(t, errors.Is(err, expected), err)
+	}
+
+	adapter, _ := dataProvider.GetSearchAdapter("google,bing,duckduckgo", dataProvider.YandexParams())
+	_, err := adapter.GetData(context.Background(), query)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "all engines failed: google: "), err.Error())
+		assert.Contains(t, err.Error(), "; bing: ")
+		assert.Contains(t, err.Error(), "; duckduckgo: ")
+		assert.True(t, errors.Is(err, serp.ErrSearchBlocked))
+		assert.True(t, errors.Is(err, serp.ErrSearchHTTPStatus))
+		var retry *serp.RetryError
+		assert.False(t, errors.As(err, &retry))
+	}
+}
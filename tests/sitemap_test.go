@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/sitemap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Sitemap_Parse(t *testing.T) {
+	urls, sitemaps, err := sitemap.Parse([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/a.xml</loc></sitemap>
+  <sitemap><loc> https://example.com/b.xml.gz </loc></sitemap>
+</sitemapindex>`))
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	assert.Equal(t, []string{"https://example.com/a.xml", "https://example.com/b.xml.gz"}, sitemaps)
+
+	urls, sitemaps, err = sitemap.Parse(gzipped(`<urlset><url><loc>https://example.com/</loc></url></urlset>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/"}, urls)
+	assert.Empty(t, sitemaps)
+
+	_, _, err = sitemap.Parse([]byte(`<html></html>`))
+	assert.Error(t, err)
+}
+
+func Test_Sitemap_Provider(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/robots.txt":
+			_, _ = fmt.Fprintf(w, "User-agent: *\nDisallow:\nSitemap: %s/index.xml\n", server.URL)
+		case "/index.xml":
+			_, _ = fmt.Fprintf(w, "<sitemapindex><sitemap><loc>%s/pages.xml.gz</loc></sitemap></sitemapindex>", server.URL)
+		case "/pages.xml.gz":
+			body := "<urlset>"
+			for i := 0; i < 20; i++ {
+				body += fmt.Sprintf("<url><loc>%s/page/%02d</loc></url>", server.URL, i)
+			}
+			body += "<url><loc>https://other.example.org/</loc></url></urlset>"
+			_, _ = w.Write(gzipped(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sites, err := dataProvider.GetAdapter(dataProvider.DataProviderSitemap).GetData(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"127.0.0.1": {
+			server.URL + "/page/00", server.URL + "/page/02", server.URL + "/page/04", server.URL + "/page/06",
+			server.URL + "/page/08", server.URL + "/page/10", server.URL + "/page/12", server.URL + "/page/14",
+			server.URL + "/page/16", server.URL + "/page/18",
+		},
+	}, sites.Items)
+}
+
+func Test_Sitemap_ProviderWithoutSitemap(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	sites, err := dataProvider.GetAdapter(dataProvider.DataProviderSitemap).GetData(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"127.0.0.1": {server.URL + "/"}}, sites.Items)
+}
+
+func gzipped(s string) []byte {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	_, _ = zw.Write([]byte(s))
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func Test_Sitemap_ProviderConcurrency(t *testing.T) {
+	dataProvider.ConfigureSitemap(10, 10, 2, time.Second)
+	defer dataProvider.ConfigureSitemap(10, 10, 4, 600*time.Second)
+
+	var inFlight, maxInFlight, requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		n := atomic.AddInt32(&inFlight, 1)
+		for m := atomic.LoadInt32(&maxInFlight); n > m && !atomic.CompareAndSwapInt32(&maxInFlight, m, n); {
+			m = atomic.LoadInt32(&maxInFlight)
+		}
+		time.Sleep(200 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	sites := make([]string, 0, 4)
+	expected := make(map[string][]string)
+	for i := 0; i < 4; i++ {
+		server, host := newHostServer(t, handler)
+		defer server.Close()
+		sites = append(sites, server.URL)
+		expected[host] = []string{server.URL + "/"}
+	}
+	query := strings.Join(sites, "\n")
+	provider := dataProvider.GetAdapter(dataProvider.DataProviderSitemap)
+
+	start := time.Now()
+	res, err := provider.GetData(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res.Items)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+	assert.True(t, time.Since(start) < 800*time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+
+	// cached for the configured ttl
+	_, err = provider.GetData(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	time.Sleep(1100 * time.Millisecond)
+	_, err = provider.GetData(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(8), atomic.LoadInt32(&requests))
+}
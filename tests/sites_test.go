@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/conf"
+	"lubyshev/go-site-benchmark/src/handlers"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type sitesHost struct {
+	Recommended int    `json:"recommended"`
+	Status      string `json:"status"`
+	Partial     bool   `json:"partial"`
+}
+
+// stubSearch makes the search engines answer by the transport,
+// the returned func restores the client.
+func stubSearch(transport http.RoundTripper) func() {
+	defaultClient := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: transport}
+	return func() {
+		http.DefaultClient = defaultClient
+	}
+}
+
+// getSites requests /sites with the budget and returns the response and its host.
+func getSites(t *testing.T, host string, budget time.Duration) (*httptest.ResponseRecorder, sitesHost) {
+	config := conf.GetConfig()
+	defaultBudget := config.SitesRequestBudget
+	config.SitesRequestBudget = budget
+	defer func() {
+		config.SitesRequestBudget = defaultBudget
+	}()
+
+	rec := httptest.NewRecorder()
+	handlers.Site(rec, httptest.NewRequest(http.MethodGet, "/sites?engine=google&format=json&search="+url.QueryEscape(host), nil))
+	res := make(map[string]sitesHost)
+	if rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	}
+	return rec, res[host]
+}
+
+func Test_Sites_Partial(t *testing.T) {
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		switch len(h.Steps) {
+		case 0:
+			return benchmark.StateUrlInProgress, 0, 1
+		case 1:
+			return benchmark.StateUrlInProgress, 1, 2
+		}
+		return benchmark.StateUrlReady, 2, 0
+	})
+	defer stubSearch(serpTransport{
+		page: fmt.Sprintf(`<html><body><a href="%s/"><h3>test</h3></a></body></html>`, server.URL),
+	})()
+	retryAfter := strconv.Itoa(int(conf.GetConfig().SitesRetryAfter / time.Second))
+
+	// no step is finished yet
+	rec, res := getSites(t, host, 200*time.Millisecond)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, sitesHost{Status: "queued"}, res)
+	assert.Equal(t, retryAfter, rec.Header().Get("Retry-After"))
+	assert.Equal(t, "true", rec.Header().Get("X-Partial-Result"))
+
+	// the first step is finished
+	for i := 0; i < 20 && res.Status == "queued"; i++ {
+		rec, res = getSites(t, host, 200*time.Millisecond)
+	}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, sitesHost{Recommended: 1, Status: "in-progress", Partial: true}, res)
+	assert.Equal(t, retryAfter, rec.Header().Get("Retry-After"))
+	assert.Equal(t, "true", rec.Header().Get("X-Partial-Result"))
+
+	// every host is done
+	rec, res = getSites(t, host, 10*time.Second)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, sitesHost{Recommended: 2, Status: "ready"}, res)
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+	assert.Empty(t, rec.Header().Get("X-Partial-Result"))
+}
+
+// blockingTransport answers when the request is done.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func Test_Sites_RequestBudget(t *testing.T) {
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(time.Second)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		if len(h.Steps) == 0 {
+			return benchmark.StateUrlInProgress, 0, 1
+		}
+		return benchmark.StateUrlReady, 1, 0
+	})
+	restore := stubSearch(serpTransport{
+		page: fmt.Sprintf(`<html><body><a href="%s/"><h3>test</h3></a></body></html>`, server.URL),
+	})
+	config := conf.GetConfig()
+	defaultBudget := config.SitesRequestBudget
+	config.SitesRequestBudget = 300 * time.Millisecond
+	defer func() {
+		config.SitesRequestBudget = defaultBudget
+	}()
+
+	// the partial result when the deadline passes
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handlers.Site(rec, httptest.NewRequest(http.MethodGet, "/sites?engine=google&search="+url.QueryEscape(host), nil))
+	elapsed := time.Since(start)
+	restore()
+	assert.True(t, elapsed >= 300*time.Millisecond && elapsed < 600*time.Millisecond, elapsed)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("X-Partial-Result"))
+	assert.Equal(t, "  0: "+host+" (queued)\n", rec.Body.String())
+
+	// the search itself is out of the budget
+	defer stubSearch(blockingTransport{})()
+	start = time.Now()
+	rec = httptest.NewRecorder()
+	handlers.Site(rec, httptest.NewRequest(http.MethodGet, "/sites?engine=google&search="+url.QueryEscape(host+" timeout"), nil))
+	elapsed = time.Since(start)
+	assert.True(t, elapsed >= 300*time.Millisecond && elapsed < 600*time.Millisecond, elapsed)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, "Search timed out", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Partial-Result"))
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Overload_StaleWhileRevalidate(t *testing.T) {
+	var requests int32
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// one step, then ready with the number of measurements
+	var measurements int32
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		if len(h.Steps) == 0 {
+			return benchmark.StateUrlInProgress, 0, 1
+		}
+		return benchmark.StateUrlReady, int(atomic.AddInt32(&measurements, 1)), 0
+	})
+
+	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	sites := &dataProvider.HostsToCheck{Items: map[string][]string{host: {server.URL + "/"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := overload.Benchmark(ctx, sites, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, benchmark.StateUrlReady, res[host].State)
+	assert.Equal(t, 1, res[host].Count)
+	assert.False(t, res[host].Stale)
+
+	// the expired result is served at once and measured again in the background
+	time.Sleep(1100 * time.Millisecond)
+	start := time.Now()
+	res, err = overload.Benchmark(ctx, sites, time.Second)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, benchmark.StateUrlReady, res[host].State)
+	assert.Equal(t, 1, res[host].Count)
+	assert.True(t, res[host].Stale)
+	assert.True(t, res[host].Age >= time.Second)
+
+	for i := 0; i < 40 && overload.Progress(sites)[host].Stale; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	res = overload.Progress(sites)
+	assert.Equal(t, benchmark.StateUrlReady, res[host].State)
+	assert.Equal(t, 2, res[host].Count)
+	assert.False(t, res[host].Stale)
+	// one connection on every round of both measurements
+	assert.Equal(t, int32(2*testStepRounds), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var strategyLimits = benchmark.StrategyLimits{
+	InitConnections: 16,
+	MaxLimit:        768,
+	MaxConnections:  912,
+	BisectTolerance: 4,
+}
+
+func Test_Strategy_Builtin(t *testing.T) {
+	for _, name := range []string{benchmark.StrategySimple, benchmark.StrategyStrong, benchmark.StrategyBisect} {
+		_, err := benchmark.GetStrategy(name)
+		assert.NoError(t, err)
+	}
+	_, err := benchmark.GetStrategy("blabla")
+	assert.Equal(t, benchmark.ErrStrategyNotFound, err)
+}
+
+func Test_Strategy_Register(t *testing.T) {
+	custom := benchmark.StrategyFunc(func(h *benchmark.StepHistory) (string, int, int) {
+		return benchmark.StateUrlReady, 1, 0
+	})
+	assert.NoError(t, benchmark.RegisterStrategy("test-custom", custom))
+	defer func() {
+		_ = benchmark.UnregisterStrategy("test-custom")
+	}()
+	assert.Equal(t, benchmark.ErrStrategyExists, benchmark.RegisterStrategy("test-custom", custom))
+	assert.Contains(t, benchmark.GetStrategyNames(), "test-custom")
+
+	s, err := benchmark.GetStrategy("test-custom")
+	assert.NoError(t, err)
+	state, count, _ := s.NextStep(&benchmark.StepHistory{})
+	assert.Equal(t, benchmark.StateUrlReady, state)
+	assert.Equal(t, 1, count)
+}
+
+func Test_Strategy_Unregister(t *testing.T) {
+	custom := benchmark.StrategyFunc(func(h *benchmark.StepHistory) (string, int, int) {
+		return benchmark.StateUrlReady, 1, 0
+	})
+	assert.NoError(t, benchmark.RegisterStrategy("test-unregister", custom))
+	assert.NoError(t, benchmark.UnregisterStrategy("test-unregister"))
+	assert.NotContains(t, benchmark.GetStrategyNames(), "test-unregister")
+	assert.Equal(t, benchmark.ErrStrategyNotFound, benchmark.UnregisterStrategy("test-unregister"))
+	assert.Equal(t, benchmark.ErrStrategyBuiltin, benchmark.UnregisterStrategy(benchmark.StrategySimple))
+	// the name is free again
+	assert.NoError(t, benchmark.RegisterStrategy("test-unregister", custom))
+	assert.NoError(t, benchmark.UnregisterStrategy("test-unregister"))
+}
+
+func Test_Strategy_Simple(t *testing.T) {
+	s, _ := benchmark.GetStrategy(benchmark.StrategySimple)
+
+	state, count, attempts := s.NextStep(&benchmark.StepHistory{Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlInProgress, state)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 16, attempts)
+
+	state, count, attempts = s.NextStep(&benchmark.StepHistory{Count: 0, Attempts: 16, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlInProgress, state)
+	assert.Equal(t, 16, count)
+	assert.Equal(t, 32, attempts)
+
+	state, count, _ = s.NextStep(&benchmark.StepHistory{Count: 16, Attempts: 32, Errors: 4, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlReady, state)
+	assert.Equal(t, 28, count)
+}
+
+func Test_Strategy_NeverFails(t *testing.T) {
+	connectionsLimits := strategyLimits
+	connectionsLimits.MaxConnections = 100
+	for _, limits := range []benchmark.StrategyLimits{strategyLimits, connectionsLimits} {
+		for _, name := range []string{benchmark.StrategySimple, benchmark.StrategyStrong, benchmark.StrategyBisect} {
+			s, _ := benchmark.GetStrategy(name)
+			h := &benchmark.StepHistory{Limits: limits}
+			state, count, attempts := s.NextStep(h)
+			for i := 0; i < 100 && state == benchmark.StateUrlInProgress; i++ {
+				assert.True(t, attempts <= limits.MaxConnections, "%s: %d attempts", name, attempts)
+				h.Count, h.Attempts, h.Errors = count, attempts, 0
+				h.Steps = append(h.Steps, benchmark.Step{Attempts: attempts})
+				state, count, attempts = s.NextStep(h)
+			}
+			assert.Equal(t, benchmark.StateUrlReady, state, name)
+			if limits.MaxConnections < limits.MaxLimit {
+				assert.Equal(t, limits.MaxConnections, count, name)
+			} else {
+				assert.Equal(t, limits.MaxLimit, count, name)
+			}
+		}
+	}
+}
+
+func Test_StepErrors(t *testing.T) {
+	cases := []struct {
+		name                                 string
+		attempts, requests, failed, expected int
+	}{
+		{"no requests", 8, 0, 0, 0},
+		{"no errors", 8, 16, 0, 0},
+		{"burst", 8, 16, 3, 2},
+		{"burst all failed", 8, 16, 16, 8},
+		{"sustained", 32, 3000, 300, 4},
+		{"sustained one error", 32, 3000, 1, 1},
+		{"sustained all failed", 32, 3000, 3000, 32},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, benchmark.StepErrors(c.attempts, c.requests, c.failed))
+		})
+	}
+}
+
+func Test_Strategy_Sustained(t *testing.T) {
+	// sustained steps send many requests per connection, the failed ones are scaled to connections
+	step := benchmark.Step{Attempts: 32, Rounds: 1, Requests: 3000, FailedRequests: 300}
+	step.Errors = benchmark.StepErrors(step.Attempts, step.Requests, step.FailedRequests)
+	assert.Equal(t, 4, step.Errors)
+	for name, expected := range map[string][3]interface{}{
+		benchmark.StrategySimple: {benchmark.StateUrlReady, 28, 0},
+		benchmark.StrategyStrong: {benchmark.StateUrlReady, 28, 0},
+		benchmark.StrategyBisect: {benchmark.StateUrlInProgress, 16, 24},
+	} {
+		s, _ := benchmark.GetStrategy(name)
+		state, count, attempts := s.NextStep(&benchmark.StepHistory{
+			Count:    16,
+			Attempts: step.Attempts,
+			Errors:   step.Errors,
+			Steps:    []benchmark.Step{{Attempts: 16, Rounds: 1, Requests: 1500}, step},
+			Limits:   strategyLimits,
+		})
+		assert.Equal(t, expected, [3]interface{}{state, count, attempts}, name)
+	}
+
+	// errors counted by requests are never recommended below zero
+	s, _ := benchmark.GetStrategy(benchmark.StrategySimple)
+	state, count, _ := s.NextStep(&benchmark.StepHistory{Count: 16, Attempts: 32, Errors: 300, Limits: strategyLimits})
+	assert.Equal(t, benchmark.StateUrlReady, state)
+	assert.Equal(t, 0, count)
+
+	// the host fails above 2 connections
+	var inFlight int32
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer atomic.AddInt32(&inFlight, -1)
+		n := atomic.AddInt32(&inFlight, 1)
+		time.Sleep(50 * time.Millisecond)
+		if n > 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	bisect, _ := benchmark.GetStrategy(benchmark.StrategyBisect)
+	setScript(host, bisect.NextStep)
+
+	overload := benchmark.GetManager().GetTest(benchmark.BenchOverload).(benchmark.OverloadTest)
+	options := testOverloadOptions
+	options.Mode = benchmark.ModeSustained
+	options.SustainDuration = 2 * time.Second
+	options.StepRounds = 1
+	overload.StopBackground()
+	assert.NoError(t, overload.StartBackground(options))
+	defer func() {
+		overload.StopBackground()
+		assert.NoError(t, overload.StartBackground(testOverloadOptions))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	sites := &dataProvider.HostsToCheck{Items: map[string][]string{host: {server.URL + "/"}}}
+	res, err := overload.Benchmark(ctx, sites, time.Minute)
+	assert.NoError(t, err)
+	if assert.Equal(t, benchmark.StateUrlReady, res[host].State) {
+		// 1 and 2 connections passed, 4 failed
+		assert.Equal(t, 2, res[host].Count)
+		// every second of the duration the connections are busy
+		if assert.Len(t, res[host].Timeline, 1) {
+			assert.Len(t, res[host].Timeline[0], 2)
+			for second, interval := range res[host].Timeline[0] {
+				assert.True(t, interval.Requests > 10, "second %d: %d requests", second, interval.Requests)
+				assert.Equal(t, 0, interval.Errors)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/handlers"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// serpTransport answers every search engine request with the page.
+type serpTransport struct {
+	page string
+}
+
+func (t serpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       ioutil.NopCloser(strings.NewReader(t.page)),
+		Request:    req,
+	}, nil
+}
+
+type streamEvent struct {
+	name string
+	data string
+}
+
+func Test_Stream_Steps(t *testing.T) {
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		switch len(h.Steps) {
+		case 0:
+			return benchmark.StateUrlInProgress, 0, 1
+		case 1:
+			return benchmark.StateUrlInProgress, 1, 2
+		}
+		return benchmark.StateUrlReady, 2, 0
+	})
+
+	// the search engine finds the test host only
+	defer stubSearch(serpTransport{
+		page: fmt.Sprintf(`<html><body><a href="%s/"><h3>test</h3></a></body></html>`, server.URL),
+	})()
+
+	stream := httptest.NewServer(http.HandlerFunc(handlers.SiteStream))
+	defer stream.Close()
+	resp, err := stream.Client().Get(stream.URL + "/sites/stream?engine=google&search=" + url.QueryEscape(host))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make([]streamEvent, 0)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			events = append(events, streamEvent{name: strings.TrimPrefix(line, "event: ")})
+		case strings.HasPrefix(line, "data: ") && len(events) > 0:
+			events[len(events)-1].data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	assert.NoError(t, scanner.Err())
+	if !assert.Len(t, events, 3) {
+		return
+	}
+
+	type step struct {
+		Host            string             `json:"host"`
+		Concurrency     int                `json:"concurrency"`
+		Requests        int                `json:"requests"`
+		PrevStatus      string             `json:"prev_status"`
+		Status          string             `json:"status"`
+		Recommended     int                `json:"recommended"`
+		NextConcurrency int                `json:"next_concurrency"`
+		Timeline        [][]map[string]int `json:"timeline"`
+	}
+	expected := []step{
+		{Host: host, Concurrency: 1, Requests: testStepRounds, PrevStatus: "queued", Status: "in-progress", Recommended: 1, NextConcurrency: 2},
+		{Host: host, Concurrency: 2, Requests: 2 * testStepRounds, PrevStatus: "in-progress", Status: "ready", Recommended: 2},
+	}
+	for i, e := range expected {
+		assert.Equal(t, "step", events[i].name)
+		actual := step{}
+		assert.NoError(t, json.Unmarshal([]byte(events[i].data), &actual))
+		assert.Len(t, actual.Timeline, testStepRounds)
+		actual.Timeline = nil
+		assert.Equal(t, e, actual)
+	}
+
+	assert.Equal(t, "result", events[2].name)
+	result := make(map[string]struct {
+		Recommended int    `json:"recommended"`
+		Status      string `json:"status"`
+	})
+	assert.NoError(t, json.Unmarshal([]byte(events[2].data), &result))
+	assert.Equal(t, 2, result[host].Recommended)
+	assert.Equal(t, "ready", result[host].Status)
+}
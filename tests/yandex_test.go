@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"lubyshev/go-site-benchmark/src/benchmark"
+	"lubyshev/go-site-benchmark/src/conf"
+	"lubyshev/go-site-benchmark/src/dataProvider"
+	"lubyshev/go-site-benchmark/src/handlers"
+	"lubyshev/go-site-benchmark/src/serp"
+	"lubyshev/go-site-benchmark/src/yandex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// yandexTransport answers by the next of the pages, a page with a path
+// is answered as if the search was redirected there.
+type yandexTransport struct {
+	pages []yandexPage
+	calls int
+}
+
+type yandexPage struct {
+	status int
+	path   string
+	body   string
+}
+
+func (t *yandexTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	page := t.pages[t.calls]
+	t.calls++
+	if page.path != "" {
+		req = req.Clone(req.Context())
+		req.URL = &url.URL{Scheme: "https", Host: req.URL.Host, Path: page.path}
+	}
+	return &http.Response{
+		StatusCode: page.status,
+		Body:       ioutil.NopCloser(strings.NewReader(page.body)),
+		Request:    req,
+	}, nil
+}
+
+func Test_Yandex_Blocked(t *testing.T) {
+	yandex.ConfigureBackoff(200*time.Millisecond, 300*time.Millisecond)
+	defer yandex.ConfigureBackoff(conf.GetConfig().YandexBackoffMin, conf.GetConfig().YandexBackoffMax)
+	transport := &yandexTransport{pages: []yandexPage{
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusOK, path: "/showcaptcha", body: `<form></form>`},
+		{status: http.StatusOK, body: `<form action="/checkcaptcha"></form>`},
+		{status: http.StatusInternalServerError},
+		{status: http.StatusOK, body: `<div class="serp-item"></div>`},
+	}}
+	defer stubSearch(transport)()
+	params := yandex.SearchParams{Region: 213, Pages: 1, NumDoc: 50, Device: yandex.DeviceTouch}
+	search := func() error {
+		_, err := yandex.GetYandexSearchResult(context.Background(), fmt.Sprintf("foobar %d", time.Now().UnixNano()), params)
+		return err
+	}
+	retryAfter := func(err error) time.Duration {
+		var retry *serp.RetryError
+		if errors.As(err, &retry) {
+			return retry.After
+		}
+		return 0
+	}
+
+	// 503 is a block like 429
+	err := search()
+	assert.True(t, errors.Is(err, serp.ErrSearchBlocked), err)
+	assert.Equal(t, 200*time.Millisecond, retryAfter(err))
+
+	// the backoff does not reach yandex
+	err = search()
+	assert.True(t, errors.Is(err, serp.ErrSearchBlocked), err)
+	assert.True(t, retryAfter(err) > 0 && retryAfter(err) <= 200*time.Millisecond, err)
+	assert.Equal(t, 1, transport.calls)
+
+	rec := httptest.NewRecorder()
+	handlers.Site(rec, httptest.NewRequest(http.MethodGet, "/sites?engine=yandex&search=foobar", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, 1, transport.calls)
+
+	// the pause is doubled up to the max
+	time.Sleep(250 * time.Millisecond)
+	err = search()
+	assert.True(t, errors.Is(err, serp.ErrSearchBlocked), err)
+	assert.Equal(t, 300*time.Millisecond, retryAfter(err))
+
+	time.Sleep(350 * time.Millisecond)
+	err = search()
+	assert.True(t, errors.Is(err, serp.ErrSearchBlocked), err)
+	assert.Equal(t, 300*time.Millisecond, retryAfter(err))
+
+	time.Sleep(350 * time.Millisecond)
+	err = search()
+	assert.True(t, errors.Is(err, serp.ErrSearchHTTPStatus), err)
+	assert.False(t, errors.Is(err, serp.ErrSearchBlocked), err)
+
+	// a successful search resets the pause
+	assert.NoError(t, search())
+	assert.Equal(t, 5, transport.calls)
+	assert.Equal(t, time.Duration(0), yandex.BlockedFor())
+}
+
+func Test_Yandex_PartialSearch(t *testing.T) {
+	yandex.ConfigureBackoff(200*time.Millisecond, 300*time.Millisecond)
+	defer yandex.ConfigureBackoff(conf.GetConfig().YandexBackoffMin, conf.GetConfig().YandexBackoffMax)
+	transport := &yandexTransport{pages: []yandexPage{
+		{status: http.StatusOK, body: `<div class="serp-item" data-cid="0"><a class="Link" href="https://example.com/a">a</a></div>` +
+			`<div class="serp-item" data-cid="1"><a class="Link" href="https://www.example.org/b">b</a></div>`},
+		{status: http.StatusOK, path: "/showcaptcha", body: `<form></form>`},
+	}}
+	defer stubSearch(transport)()
+	params := yandex.SearchParams{Region: 213, Pages: 2, NumDoc: 50, Device: yandex.DeviceTouch}
+	adapter, err := dataProvider.GetSearchAdapter("yandex", params)
+	assert.NoError(t, err)
+	query := fmt.Sprintf("foobar %d", time.Now().UnixNano())
+
+	// the first page is kept, the block of the second one starts the backoff
+	sites, err := adapter.GetData(context.Background(), query)
+	if assert.NoError(t, err) {
+		assert.True(t, sites.Partial)
+		assert.Equal(t, map[string][]string{
+			"example.com": {"https://example.com/a"},
+			"example.org": {"https://www.example.org/b"},
+		}, sites.Items)
+	}
+	assert.True(t, yandex.BlockedFor() > 0)
+
+	// the partial result is not cached
+	_, err = adapter.GetData(context.Background(), query)
+	assert.True(t, errors.Is(err, serp.ErrSearchBlocked), err)
+	assert.Equal(t, 2, transport.calls)
+
+	// the handler marks the hosts of a partial search
+	time.Sleep(250 * time.Millisecond)
+	server, host := newHostServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	setScript(host, func(h *benchmark.StepHistory) (string, int, int) {
+		return benchmark.StateUrlReady, 1, 0
+	})
+	transport.pages = append(transport.pages,
+		yandexPage{status: http.StatusOK, body: fmt.Sprintf(`<div class="serp-item" data-cid="0"><a class="Link" href="%s/">a</a></div>`, server.URL)},
+		yandexPage{status: http.StatusOK, path: "/showcaptcha", body: `<form></form>`},
+	)
+	rec := httptest.NewRecorder()
+	handlers.Site(rec, httptest.NewRequest(http.MethodGet, "/sites?engine=yandex&pages=2&search="+url.QueryEscape(query), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("X-Partial-Search"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "  1: "+host+" (measured"), rec.Body.String())
+
+	// let the backoff end for the next tests
+	time.Sleep(350 * time.Millisecond)
+}